@@ -0,0 +1,387 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/mongo"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/trace"
+
+	"github.com/orijtech/media-search/ratelimit"
+	"github.com/orijtech/media-search/rpc"
+	"github.com/orijtech/media-search/telemetry"
+)
+
+// searchEvent is the record of a single search, as kept in the search log.
+type searchEvent struct {
+	Query string `json:"query" bson:"query,omitempty"`
+	// Key is the canonical form of Query, see normalizeQuery.
+	Key         string  `json:"key" bson:"key,omitempty"`
+	Hit         bool    `json:"hit" bson:"hit"`
+	LatencyMs   float64 `json:"latency_ms" bson:"latency_ms"`
+	ResultCount int64   `json:"result_count" bson:"result_count"`
+	ClientID    string  `json:"client_id,omitempty" bson:"client_id,omitempty"`
+	TraceID     string  `json:"trace_id,omitempty" bson:"trace_id,omitempty"`
+	// Err is the rpc.Code that the search failed with, if it did.
+	Err  string    `json:"err,omitempty" bson:"err,omitempty"`
+	Time time.Time `json:"ts" bson:"ts,omitempty"`
+}
+
+const (
+	defaultAnalyticsWindow = 24 * time.Hour
+	maxAnalyticsWindow     = 30 * 24 * time.Hour
+	defaultAnalyticsLimit  = 20
+)
+
+var droppedSearchEvents = stats.Int64("search_events_dropped", "the number of search events dropped because the writer fell behind", stats.UnitNone)
+
+// searchEvents buffers events so that the search handler
+// never waits on MongoDB just to record what it did.
+var searchEvents = make(chan *searchEvent, 1024)
+
+// searchEventsMu guards closing searchEvents against recording more events.
+var searchEventsMu sync.RWMutex
+var searchEventsClosed bool
+
+// searchEventsWritten is closed once every queued event was written.
+var searchEventsWritten = make(chan struct{})
+
+func newSearchEvent(ctx context.Context, r *http.Request, query string) *searchEvent {
+	return &searchEvent{
+		Query:    query,
+		Key:      normalizeQuery(query),
+		ClientID: ratelimit.ClientKey(r),
+		TraceID:  trace.FromContext(ctx).SpanContext().TraceID.String(),
+		Time:     time.Now(),
+	}
+}
+
+// recordSearchEvent queues ev for writing, dropping it if the queue is full.
+func recordSearchEvent(ev *searchEvent) {
+	ev.LatencyMs = float64(time.Since(ev.Time)) / float64(time.Millisecond)
+	searchEventsMu.RLock()
+	defer searchEventsMu.RUnlock()
+	if searchEventsClosed {
+		stats.Record(context.Background(), droppedSearchEvents.M(1))
+		return
+	}
+	select {
+	case searchEvents <- ev:
+	default:
+		stats.Record(context.Background(), droppedSearchEvents.M(1))
+	}
+}
+
+// writeSearchEvents drains the queue of search events into the
+// search log, until flushSearchEvents is invoked.
+func writeSearchEvents() {
+	defer close(searchEventsWritten)
+	for ev := range searchEvents {
+		ctx, span := trace.StartSpan(context.Background(), "write-search-event")
+		start := time.Now()
+		_, err := searchLogCollection.InsertOne(ctx, ev)
+		telemetry.RecordLatency(ctx, start, "search_event_insert", telemetry.ProviderMongoDB, telemetry.ResultOf(err))
+		if err != nil {
+			stats.Record(ctx, mongoErrors.M(1))
+			logger.WarnContext(ctx, "Writing the search event error", "query", ev.Query, "err", err)
+		}
+		span.End()
+	}
+}
+
+// flushSearchEvents stops recording search events and
+// waits for those already queued to be written.
+func flushSearchEvents(ctx context.Context) error {
+	searchEventsMu.Lock()
+	if !searchEventsClosed {
+		searchEventsClosed = true
+		close(searchEvents)
+	}
+	searchEventsMu.Unlock()
+
+	select {
+	case <-searchEventsWritten:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// countResults returns the number of items in a JSON encoded []*rpc.SearchResult.
+func countResults(blob []byte) int64 {
+	var results []*rpc.SearchResult
+	if err := json.Unmarshal(blob, &results); err != nil {
+		return 0
+	}
+	n := 0
+	for _, result := range results {
+		if result != nil {
+			n += len(result.Items)
+		}
+	}
+	return int64(n)
+}
+
+// indexSearchLog indexes the search log by the time of its events, which
+// every report and the suggestions select by, and has MongoDB delete
+// them once they are older than retention.
+func indexSearchLog(ctx context.Context, retention time.Duration) {
+	_, err := searchLogCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.NewDocument(bson.EC.Int32("ts", 1)),
+		Options: bson.NewDocument(
+			bson.EC.String("name", "ts_ttl"),
+			bson.EC.Int32("expireAfterSeconds", int32(retention/time.Second)),
+		),
+	})
+	if err != nil {
+		// Such as when the retention changed, which takes a collMod of the index.
+		stats.Record(ctx, mongoErrors.M(1))
+		logger.ErrorContext(ctx, "Indexing the search log error", "retention", retention, "err", err)
+	}
+}
+
+// analyticsSince returns the start of the window requested by r.
+func analyticsSince(r *http.Request) (time.Time, error) {
+	window := defaultAnalyticsWindow
+	if s := r.URL.Query().Get("window"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return time.Time{}, rpc.Errorf(rpc.CodeInvalidQuery, "Expecting a positive duration window, got %q", s)
+		}
+		window = d
+	}
+	if window > maxAnalyticsWindow {
+		window = maxAnalyticsWindow
+	}
+	return time.Now().Add(-window), nil
+}
+
+type queryCount struct {
+	Key   string `json:"key" bson:"_id"`
+	Count int64  `json:"count" bson:"count"`
+}
+
+// countSearchKeys counts the search events since the given time that also
+// match the given elements by their key, most frequent first, and returns
+// at most limit of them unless limit is 0. MongoDB does the counting,
+// so only the counts are ever read, however long the window.
+func countSearchKeys(ctx context.Context, since time.Time, limit int64, match ...*bson.Element) ([]*queryCount, error) {
+	match = append([]*bson.Element{
+		bson.EC.SubDocumentFromElements("ts", bson.EC.Time("$gte", since)),
+		bson.EC.SubDocumentFromElements("key", bson.EC.Boolean("$exists", true)),
+	}, match...)
+	pipeline := bson.NewArray(
+		bson.VC.DocumentFromElements(bson.EC.SubDocumentFromElements("$match", match...)),
+		bson.VC.DocumentFromElements(bson.EC.SubDocumentFromElements("$group",
+			bson.EC.String("_id", "$key"),
+			bson.EC.SubDocumentFromElements("count", bson.EC.Int64("$sum", 1)),
+		)),
+		bson.VC.DocumentFromElements(bson.EC.SubDocumentFromElements("$sort",
+			bson.EC.Int32("count", -1),
+			bson.EC.Int32("_id", 1),
+		)),
+	)
+	if limit > 0 {
+		pipeline.Append(bson.VC.DocumentFromElements(bson.EC.Int64("$limit", limit)))
+	}
+
+	start := time.Now()
+	cur, err := searchLogCollection.Aggregate(ctx, pipeline)
+	telemetry.RecordLatency(ctx, start, "search_log_aggregate", telemetry.ProviderMongoDB, telemetry.ResultOf(err))
+	if err != nil {
+		stats.Record(ctx, mongoErrors.M(1))
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var qcl []*queryCount
+	for cur.Next(ctx) {
+		qc := new(queryCount)
+		if err := cur.Decode(qc); err == nil && qc.Key != "" {
+			qcl = append(qcl, qc)
+		}
+	}
+	return qcl, cur.Err()
+}
+
+func analyticsLimit(r *http.Request) int64 {
+	if n, err := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64); err == nil && n > 0 {
+		return n
+	}
+	return defaultAnalyticsLimit
+}
+
+func writeAnalytics(w http.ResponseWriter, since time.Time, report interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	_ = enc.Encode(map[string]interface{}{
+		"since":  since,
+		"report": report,
+	})
+}
+
+func topQueries(w http.ResponseWriter, r *http.Request) {
+	ctx, span := trace.StartSpan(r.Context(), "/analytics/top-queries")
+	defer span.End()
+
+	since, err := analyticsSince(r)
+	if err != nil {
+		rpc.WriteHTTPError(w, err)
+		return
+	}
+	qcl, err := countSearchKeys(ctx, since, analyticsLimit(r))
+	if err != nil {
+		rpc.WriteHTTPError(w, err)
+		return
+	}
+	writeAnalytics(w, since, qcl)
+}
+
+func zeroResultQueries(w http.ResponseWriter, r *http.Request) {
+	ctx, span := trace.StartSpan(r.Context(), "/analytics/zero-results")
+	defer span.End()
+
+	since, err := analyticsSince(r)
+	if err != nil {
+		rpc.WriteHTTPError(w, err)
+		return
+	}
+	qcl, err := countSearchKeys(ctx, since, analyticsLimit(r),
+		bson.EC.Int64("result_count", 0),
+		// Failed searches are not zero-result searches.
+		bson.EC.SubDocumentFromElements("err", bson.EC.Boolean("$exists", false)),
+	)
+	if err != nil {
+		rpc.WriteHTTPError(w, err)
+		return
+	}
+	writeAnalytics(w, since, qcl)
+}
+
+type hitRatio struct {
+	Start  time.Time `json:"start"`
+	Hits   int64     `json:"hits"`
+	Misses int64     `json:"misses"`
+	Ratio  float64   `json:"ratio"`
+}
+
+func (hr *hitRatio) add(hits, misses int64) {
+	hr.Hits += hits
+	hr.Misses += misses
+	if total := hr.Hits + hr.Misses; total > 0 {
+		hr.Ratio = float64(hr.Hits) / float64(total)
+	}
+}
+
+// hitBucket is a result of the aggregation of cacheHitRatio,
+// Index being the number of buckets between since and Start.
+type hitBucket struct {
+	Index  float64 `bson:"_id"`
+	Hits   int64   `bson:"hits"`
+	Misses int64   `bson:"misses"`
+}
+
+func cacheHitRatio(w http.ResponseWriter, r *http.Request) {
+	ctx, span := trace.StartSpan(r.Context(), "/analytics/hit-ratio")
+	defer span.End()
+
+	bucket := time.Hour
+	if s := r.URL.Query().Get("bucket"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d < time.Minute {
+			rpc.WriteHTTPError(w, rpc.Errorf(rpc.CodeInvalidQuery, "Expecting a bucket duration of at least 1m"))
+			return
+		}
+		bucket = d
+	}
+
+	since, err := analyticsSince(r)
+	if err != nil {
+		rpc.WriteHTTPError(w, err)
+		return
+	}
+
+	// Failed searches were neither hits nor misses.
+	match := bson.NewDocument(
+		bson.EC.SubDocumentFromElements("ts", bson.EC.Time("$gte", since)),
+		bson.EC.SubDocumentFromElements("err", bson.EC.Boolean("$exists", false)),
+	)
+	// The index of the bucket is floor((ts - since) / bucket), in milliseconds.
+	index := bson.EC.SubDocumentFromElements("_id",
+		bson.EC.SubDocumentFromElements("$floor",
+			bson.EC.ArrayFromElements("$divide",
+				bson.VC.DocumentFromElements(bson.EC.ArrayFromElements("$subtract",
+					bson.VC.String("$ts"),
+					bson.VC.Time(since),
+				)),
+				bson.VC.Int64(int64(bucket/time.Millisecond)),
+			),
+		),
+	)
+	hits := bson.EC.SubDocumentFromElements("hits", bson.EC.SubDocumentFromElements("$sum",
+		bson.EC.ArrayFromElements("$cond", bson.VC.String("$hit"), bson.VC.Int64(1), bson.VC.Int64(0)),
+	))
+	misses := bson.EC.SubDocumentFromElements("misses", bson.EC.SubDocumentFromElements("$sum",
+		bson.EC.ArrayFromElements("$cond", bson.VC.String("$hit"), bson.VC.Int64(0), bson.VC.Int64(1)),
+	))
+	pipeline := bson.NewArray(
+		bson.VC.DocumentFromElements(bson.EC.SubDocument("$match", match)),
+		bson.VC.DocumentFromElements(bson.EC.SubDocumentFromElements("$group",
+			index, hits, misses,
+		)),
+		bson.VC.DocumentFromElements(bson.EC.SubDocumentFromElements("$sort", bson.EC.Int32("_id", 1))),
+	)
+	start := time.Now()
+	cur, err := searchLogCollection.Aggregate(ctx, pipeline)
+	telemetry.RecordLatency(ctx, start, "search_log_aggregate", telemetry.ProviderMongoDB, telemetry.ResultOf(err))
+	if err != nil {
+		stats.Record(ctx, mongoErrors.M(1))
+		rpc.WriteHTTPError(w, err)
+		return
+	}
+	defer cur.Close(ctx)
+
+	total := &hitRatio{Start: since}
+	var buckets []*hitRatio
+	for cur.Next(ctx) {
+		hb := new(hitBucket)
+		if err := cur.Decode(hb); err != nil || hb.Index < 0 {
+			continue
+		}
+		total.add(hb.Hits, hb.Misses)
+		i := int(hb.Index)
+		for len(buckets) <= i {
+			buckets = append(buckets, &hitRatio{Start: since.Add(time.Duration(len(buckets)) * bucket)})
+		}
+		buckets[i].add(hb.Hits, hb.Misses)
+	}
+	if err := cur.Err(); err != nil {
+		rpc.WriteHTTPError(w, err)
+		return
+	}
+	writeAnalytics(w, since, map[string]interface{}{
+		"total":   total,
+		"buckets": buckets,
+	})
+}
@@ -0,0 +1,172 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package v1 is the version 1 of the public JSON schema of the search
+// results, served at /v1/search. Unlike the JSON of the structs generated
+// from rpc/defs.proto, it doesn't change when defs.proto does: fields are
+// only ever added to it, a breaking change makes a v2.
+//
+// Every field is always present. The strings YouTube left out are empty,
+// the optional values are null rather than left out, see Thumbnails, and
+// the lists are empty rather than null. search.schema.json is its JSON Schema.
+package v1
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/orijtech/media-search/rpc"
+)
+
+// Version is the value of SearchResponse.Version.
+const Version = "v1"
+
+// SearchResponse is the response to a search.
+type SearchResponse struct {
+	Version string `json:"version"`
+	// Query are the keywords searched for.
+	Query string `json:"query"`
+	// Items are the items found, in the order of their pages.
+	Items []*Item `json:"items"`
+	// Errors are those of the pages that failed, whose items are missing.
+	Errors []*PageError `json:"errors"`
+}
+
+// The kinds of the items found.
+const (
+	KindVideo    = "video"
+	KindChannel  = "channel"
+	KindPlaylist = "playlist"
+)
+
+// Item is a video, channel or playlist found.
+type Item struct {
+	// Kind is KindVideo, KindChannel or KindPlaylist.
+	Kind string `json:"kind"`
+	// ID is the YouTube ID of the video, channel or playlist.
+	ID          string `json:"id"`
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// PublishedAt is an RFC 3339 time, or null if unknown.
+	PublishedAt *string    `json:"published_at"`
+	Channel     Channel    `json:"channel"`
+	Thumbnails  Thumbnails `json:"thumbnails"`
+	// Page is the index of the page of results the item is on.
+	Page int `json:"page"`
+}
+
+// Channel is the channel that published an item, the item itself for a channel.
+type Channel struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// Thumbnails are the thumbnails of an item by size, each null if the item
+// has none of that size. An item may have no thumbnail at all, clients
+// wanting a single one pick the first of High, Medium and Default not null.
+type Thumbnails struct {
+	Default *Thumbnail `json:"default"`
+	Medium  *Thumbnail `json:"medium"`
+	High    *Thumbnail `json:"high"`
+}
+
+// Thumbnail is an image of an item, whose Width and Height are null if unknown.
+type Thumbnail struct {
+	URL    string `json:"url"`
+	Width  *int64 `json:"width"`
+	Height *int64 `json:"height"`
+}
+
+// PageError is why a page of results failed, see rpc.Code.
+type PageError struct {
+	Page    int    `json:"page"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// FromResults returns the response to the search for query that found results.
+func FromResults(query string, results []*rpc.SearchResult) *SearchResponse {
+	sr := &SearchResponse{
+		Version: Version,
+		Query:   query,
+		Items:   []*Item{},
+		Errors:  []*PageError{},
+	}
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		page := int(result.GetIndex())
+		if detail := result.GetError(); detail != nil {
+			sr.Errors = append(sr.Errors, &PageError{Page: page, Code: detail.GetCode(), Message: detail.GetMessage()})
+		} else if result.GetErr() != "" {
+			sr.Errors = append(sr.Errors, &PageError{Page: page, Code: string(rpc.CodeInternal), Message: result.GetErr()})
+		}
+		for _, yr := range result.GetItems() {
+			if item := fromYouTubeResult(yr); item != nil {
+				item.Page = page
+				sr.Items = append(sr.Items, item)
+			}
+		}
+	}
+	return sr
+}
+
+// fromYouTubeResult returns the Item of yr, or nil if it is of no known kind.
+func fromYouTubeResult(yr *rpc.YouTubeResult) *Item {
+	id, snip := yr.GetId(), yr.GetSnippet()
+	item := &Item{
+		Title:       snip.GetTitle(),
+		Description: snip.GetDescription(),
+		Channel:     Channel{ID: snip.GetChannelId(), Title: snip.GetChannelTitle()},
+		Thumbnails: Thumbnails{
+			Default: fromThumbnail(snip.GetThumbnails()["default"]),
+			Medium:  fromThumbnail(snip.GetThumbnails()["medium"]),
+			High:    fromThumbnail(snip.GetThumbnails()["high"]),
+		},
+	}
+	if at := snip.GetPublishedAt(); at != "" {
+		item.PublishedAt = &at
+	}
+	switch kind := strings.TrimPrefix(id.GetKind(), "youtube#"); {
+	case id.GetVideoId() != "":
+		item.Kind, item.ID = KindVideo, id.GetVideoId()
+		item.URL = "https://www.youtube.com/watch?v=" + url.QueryEscape(item.ID)
+	case id.GetPlaylistId() != "":
+		item.Kind, item.ID = KindPlaylist, id.GetPlaylistId()
+		item.URL = "https://www.youtube.com/playlist?list=" + url.QueryEscape(item.ID)
+	case kind == KindChannel && snip.GetChannelId() != "":
+		// The ID of a channel found is only in its snippet.
+		item.Kind, item.ID = KindChannel, snip.GetChannelId()
+		item.URL = "https://www.youtube.com/channel/" + url.PathEscape(item.ID)
+	default:
+		return nil
+	}
+	return item
+}
+
+func fromThumbnail(th *rpc.Thumbnail) *Thumbnail {
+	if th.GetUrl() == "" {
+		return nil
+	}
+	t := &Thumbnail{URL: th.GetUrl()}
+	if w := th.GetWidth(); w > 0 {
+		t.Width = &w
+	}
+	if h := th.GetHeight(); h > 0 {
+		t.Height = &h
+	}
+	return t
+}
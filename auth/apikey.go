@@ -0,0 +1,52 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"github.com/orijtech/media-search/secrets"
+)
+
+// APIKeyHeader is the header carrying a static API key.
+const APIKeyHeader = "X-API-Key"
+
+// APIKeys returns an Authenticator of the static API keys of keys, each of
+// the form principal:key, or just key for a principal named after its hash.
+func APIKeys(keys *secrets.Keyring) Authenticator {
+	return &apiKeys{keys: keys}
+}
+
+type apiKeys struct {
+	keys *secrets.Keyring
+}
+
+var errInvalidAPIKey = errors.New("auth: invalid API key")
+
+func (ak *apiKeys) Authenticate(r *http.Request) (*Principal, error) {
+	given := r.Header.Get(APIKeyHeader)
+	if given == "" {
+		return nil, ErrNoCredentials
+	}
+	for _, secret := range ak.keys.Keys() {
+		id, key := splitID(secret)
+		if subtle.ConstantTimeCompare([]byte(given), []byte(key)) == 1 {
+			return &Principal{ID: id, Method: MethodAPIKey}, nil
+		}
+	}
+	return nil, errInvalidAPIKey
+}
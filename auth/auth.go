@@ -0,0 +1,173 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package auth authenticates the requests to the public API with static
+// API keys, HMAC signatures or JWT bearer tokens, and propagates who made
+// them, the principal, to the traces, the metrics and the backends.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.opencensus.io/trace"
+
+	"github.com/orijtech/media-search/rpc"
+)
+
+// The methods that principals are authenticated with.
+const (
+	MethodAPIKey = "api_key"
+	MethodHMAC   = "hmac"
+	MethodJWT    = "jwt"
+)
+
+// Principal is who a request was made by.
+type Principal struct {
+	ID     string
+	Method string
+}
+
+// metricID returns the value of the KeyPrincipal tag of p. The API and
+// HMAC keys are configured, hence few, whereas a JWT issuer may vouch
+// for any number of subjects, so those are only told apart by their
+// spans: as a tag, each would make a time series of its own.
+func (p *Principal) metricID() string {
+	switch p.Method {
+	case MethodAPIKey, MethodHMAC:
+		return p.ID
+	default:
+		return p.Method
+	}
+}
+
+// ErrNoCredentials is returned by an Authenticator for a request
+// that doesn't carry the kind of credentials that it checks.
+var ErrNoCredentials = errors.New("auth: no credentials")
+
+// Authenticator authenticates requests with one kind of credentials.
+type Authenticator interface {
+	// Authenticate returns the principal that r was made by, ErrNoCredentials
+	// if r carries none of the credentials it checks or another error if
+	// they are invalid. It must leave the body of r to be read again.
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+var (
+	KeyPrincipal = mustKey("principal")
+	KeyMethod    = mustKey("auth_method")
+	KeyResult    = mustKey("result")
+
+	authentications = stats.Int64("authentications", "The number of requests authenticated or rejected", "1")
+)
+
+// Views are the views for the metrics recorded by this package.
+var Views = []*view.View{
+	{
+		Name: "authentications", Description: "requests authenticated or rejected",
+		Measure: authentications, Aggregation: view.Count(), TagKeys: []tag.Key{KeyPrincipal, KeyMethod, KeyResult},
+	},
+}
+
+// Chain tries each of its authenticators in turn. An empty
+// Chain disables authentication, letting every request through.
+type Chain []Authenticator
+
+// Authenticate returns the principal that the first authenticator
+// that finds credentials in r authenticates, or its error.
+func (c Chain) Authenticate(r *http.Request) (*Principal, error) {
+	for _, a := range c {
+		p, err := a.Authenticate(r)
+		if err != ErrNoCredentials {
+			return p, err
+		}
+	}
+	return nil, ErrNoCredentials
+}
+
+// Require rejects the requests to h that fail to authenticate with
+// UNAUTHENTICATED. The principal of those let through is added to
+// their context, see FromContext, to their span and to their tags.
+func (c Chain) Require(h http.Handler) http.Handler {
+	if len(c) == 0 {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		p, err := c.Authenticate(r)
+		if err != nil {
+			result := "invalid"
+			if err == ErrNoCredentials {
+				result = "missing"
+			}
+			tctx, _ := tag.New(ctx, tag.Upsert(KeyResult, result))
+			stats.Record(tctx, authentications.M(1))
+			trace.FromContext(ctx).Annotate([]trace.Attribute{trace.StringAttribute("error", err.Error())}, "Authentication failed")
+			rpc.WriteHTTPError(w, rpc.Errorf(rpc.CodeUnauthenticated, "%v", err))
+			return
+		}
+
+		ctx = NewContext(ctx, p)
+		tctx, _ := tag.New(ctx, tag.Upsert(KeyResult, "ok"))
+		stats.Record(tctx, authentications.M(1))
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+type principalKey struct{}
+
+// NewContext returns a copy of ctx carrying p, which is also added to the
+// attributes of the span of ctx and, bounded as by metricID, to its tags.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	trace.FromContext(ctx).AddAttributes(
+		trace.StringAttribute("auth.principal", p.ID),
+		trace.StringAttribute("auth.method", p.Method),
+	)
+	if tctx, err := tag.New(ctx, tag.Upsert(KeyPrincipal, p.metricID()), tag.Upsert(KeyMethod, p.Method)); err == nil {
+		ctx = tctx
+	}
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal of ctx, nil if there is none.
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// splitID splits a secret of the form id:secret. A secret without an id
+// is identified by a hash of it instead, for it never to be exported.
+func splitID(secret string) (id, value string) {
+	if i := strings.IndexByte(secret, ':'); i > 0 {
+		return secret[:i], secret[i+1:]
+	}
+	sum := sha256.Sum256([]byte(secret))
+	return "key-" + hex.EncodeToString(sum[:4]), secret
+}
+
+func mustKey(sk string) tag.Key {
+	k, err := tag.NewKey(sk)
+	if err != nil {
+		log.Fatalf("Creating new key %q error: %v", sk, err)
+	}
+	return k
+}
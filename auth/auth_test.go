@@ -0,0 +1,374 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+
+	"github.com/orijtech/media-search/secrets"
+)
+
+func newTestKeyring(t *testing.T, ss ...string) *secrets.Keyring {
+	kr, err := secrets.NewKeyring(secrets.Static(ss...))
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	return kr
+}
+
+// authenticate serves r with h required to be authenticated by c,
+// returning the status and the principal that h was called with.
+func authenticate(c Chain, r *http.Request) (int, *Principal) {
+	var p *Principal
+	h := c.Require(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p = FromContext(r.Context())
+		if _, err := ioutil.ReadAll(r.Body); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec.Code, p
+}
+
+func TestAPIKeys(t *testing.T) {
+	chain := Chain{APIKeys(newTestKeyring(t, "alice:s3cr3t", "anonymous"))}
+
+	tests := []struct {
+		name       string
+		key        string
+		wantStatus int
+		wantID     string
+	}{
+		{"no credentials", "", http.StatusUnauthorized, ""},
+		{"valid key", "s3cr3t", http.StatusOK, "alice"},
+		{"key without a principal", "anonymous", http.StatusOK, "key-"},
+		{"invalid key", "s3cr3", http.StatusUnauthorized, ""},
+		{"principal instead of the key", "alice", http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/search?q=ocean", nil)
+		if tt.key != "" {
+			r.Header.Set(APIKeyHeader, tt.key)
+		}
+		status, p := authenticate(chain, r)
+		if status != tt.wantStatus {
+			t.Errorf("%s: got status %d, want %d", tt.name, status, tt.wantStatus)
+			continue
+		}
+		if tt.wantStatus != http.StatusOK {
+			continue
+		}
+		if p == nil || p.Method != MethodAPIKey || !strings.HasPrefix(p.ID, tt.wantID) {
+			t.Errorf("%s: got principal %+v, want ID %q and method %q", tt.name, p, tt.wantID, MethodAPIKey)
+		}
+	}
+}
+
+// signRequest signs r, with body, as of ts under secret as keyID.
+func signRequest(r *http.Request, body, keyID, secret string, ts time.Time) {
+	sum := sha256.Sum256([]byte(body))
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(r.Method + "\n" + r.URL.RequestURI() + "\n" + timestamp + "\n" + hex.EncodeToString(sum[:])))
+	r.Header.Set(HMACKeyIDHeader, keyID)
+	r.Header.Set(HMACTimestampHeader, timestamp)
+	r.Header.Set(HMACSignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+}
+
+func TestHMAC(t *testing.T) {
+	const body = `{"q":"ocean"}`
+	now := time.Now()
+
+	tests := []struct {
+		name       string
+		body       string
+		sign       func(r *http.Request)
+		wantStatus int
+	}{
+		{
+			name:       "valid signature",
+			body:       body,
+			sign:       func(r *http.Request) { signRequest(r, body, "bob", "hmacsecret", now) },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "tampered body",
+			body:       body + " ",
+			sign:       func(r *http.Request) { signRequest(r, body, "bob", "hmacsecret", now.Add(-time.Second)) },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "tampered path",
+			body: body,
+			sign: func(r *http.Request) {
+				signRequest(r, body, "bob", "hmacsecret", now.Add(-2*time.Second))
+				r.URL.RawQuery = "q=other"
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong secret",
+			body:       body,
+			sign:       func(r *http.Request) { signRequest(r, body, "bob", "guess", now.Add(-3*time.Second)) },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "unknown key id",
+			body:       body,
+			sign:       func(r *http.Request) { signRequest(r, body, "eve", "hmacsecret", now.Add(-4*time.Second)) },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "timestamp too old",
+			body:       body,
+			sign:       func(r *http.Request) { signRequest(r, body, "bob", "hmacsecret", now.Add(-2*time.Minute)) },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "timestamp too far ahead",
+			body:       body,
+			sign:       func(r *http.Request) { signRequest(r, body, "bob", "hmacsecret", now.Add(2*time.Minute)) },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "missing signature",
+			body: body,
+			sign: func(r *http.Request) {
+				signRequest(r, body, "bob", "hmacsecret", now.Add(-5*time.Second))
+				r.Header.Del(HMACSignatureHeader)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "largest body",
+			body:       strings.Repeat("a", MaxSignedBodyBytes),
+			sign:       nil,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "body too large",
+			body:       strings.Repeat("a", MaxSignedBodyBytes+1),
+			sign:       nil,
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	chain := Chain{HMAC(newTestKeyring(t, "bob:hmacsecret"), time.Minute)}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/search?q=ocean", strings.NewReader(tt.body))
+		if tt.sign != nil {
+			tt.sign(r)
+		} else {
+			signRequest(r, tt.body, "bob", "hmacsecret", now)
+		}
+		status, p := authenticate(chain, r)
+		if status != tt.wantStatus {
+			t.Errorf("%s: got status %d, want %d", tt.name, status, tt.wantStatus)
+			continue
+		}
+		if status == http.StatusOK && (p == nil || p.ID != "bob" || p.Method != MethodHMAC) {
+			t.Errorf("%s: got principal %+v, want bob by %q", tt.name, p, MethodHMAC)
+		}
+	}
+}
+
+func TestHMACBodyStillReadable(t *testing.T) {
+	const body = `{"q":"ocean"}`
+	auth := HMAC(newTestKeyring(t, "bob:hmacsecret"), time.Minute)
+	r := httptest.NewRequest("POST", "/search", strings.NewReader(body))
+	signRequest(r, body, "bob", "hmacsecret", time.Now())
+	if _, err := auth.Authenticate(r); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	got, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != body {
+		t.Errorf("read the body %q after authenticating, want %q", got, body)
+	}
+}
+
+func TestHMACReplay(t *testing.T) {
+	const body = `{"q":"ocean"}`
+	auth := HMAC(newTestKeyring(t, "bob:hmacsecret"), time.Minute)
+	now := time.Now()
+	signed := func(ts time.Time) *http.Request {
+		r := httptest.NewRequest("POST", "/search", strings.NewReader(body))
+		signRequest(r, body, "bob", "hmacsecret", ts)
+		return r
+	}
+
+	if _, err := auth.Authenticate(signed(now)); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	if _, err := auth.Authenticate(signed(now)); err != errReplayedSignature {
+		t.Errorf("replayed request: got %v, want %v", err, errReplayedSignature)
+	}
+	// The same request signed at another time isn't a replay.
+	if _, err := auth.Authenticate(signed(now.Add(-time.Second))); err != nil {
+		t.Errorf("request signed a second earlier: %v", err)
+	}
+
+	// Signatures are forgotten once their timestamp is out of the skew.
+	ha := auth.(*hmacAuth)
+	ha.mu.Lock()
+	for sig := range ha.seen {
+		ha.seen[sig] = now.Add(-time.Second)
+	}
+	ha.lastSweep = time.Time{}
+	ha.mu.Unlock()
+	if !ha.firstUse([]byte("other"), now.Add(time.Minute)) {
+		t.Fatal("firstUse of a new signature reported a replay")
+	}
+	if n := len(ha.seen); n != 1 {
+		t.Errorf("remembering %d signatures after the sweep, want 1", n)
+	}
+}
+
+func TestJWT(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	jwksFile := filepath.Join(dir, "jwks.json")
+	jwks := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: &key.PublicKey, KeyID: "k1", Algorithm: string(jose.ES256), Use: "sig"},
+	}}
+	blob, err := json.Marshal(jwks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(jwksFile, blob, 0600); err != nil {
+		t.Fatal(err)
+	}
+	j, err := NewJWT(jwksFile, "issuer", "audience")
+	if err != nil {
+		t.Fatalf("NewJWT: %v", err)
+	}
+
+	valid := jwt.Claims{
+		Subject:  "carol",
+		Issuer:   "issuer",
+		Audience: jwt.Audience{"audience"},
+		Expiry:   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+	tests := []struct {
+		name       string
+		key        *ecdsa.PrivateKey
+		claims     func(c *jwt.Claims)
+		wantStatus int
+	}{
+		{"valid token", key, func(*jwt.Claims) {}, http.StatusOK},
+		{"expired", key, func(c *jwt.Claims) { c.Expiry = jwt.NewNumericDate(time.Now().Add(-time.Hour)) }, http.StatusUnauthorized},
+		{"never expires", key, func(c *jwt.Claims) { c.Expiry = nil }, http.StatusUnauthorized},
+		{"no subject", key, func(c *jwt.Claims) { c.Subject = "" }, http.StatusUnauthorized},
+		{"other issuer", key, func(c *jwt.Claims) { c.Issuer = "other" }, http.StatusUnauthorized},
+		{"other audience", key, func(c *jwt.Claims) { c.Audience = jwt.Audience{"other"} }, http.StatusUnauthorized},
+		{"signed with another key", other, func(*jwt.Claims) {}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		claims := valid
+		tt.claims(&claims)
+		signer, err := jose.NewSigner(jose.SigningKey{
+			Algorithm: jose.ES256,
+			Key:       jose.JSONWebKey{Key: tt.key, KeyID: "k1"},
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := jwt.Signed(signer).Claims(claims).Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := httptest.NewRequest("GET", "/trending", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		status, p := authenticate(Chain{j}, r)
+		if status != tt.wantStatus {
+			t.Errorf("%s: got status %d, want %d", tt.name, status, tt.wantStatus)
+			continue
+		}
+		if status == http.StatusOK && (p == nil || p.ID != "carol" || p.Method != MethodJWT) {
+			t.Errorf("%s: got principal %+v, want carol by %q", tt.name, p, MethodJWT)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	chain := Chain{
+		APIKeys(newTestKeyring(t, "alice:s3cr3t")),
+		HMAC(newTestKeyring(t, "bob:hmacsecret"), time.Minute),
+	}
+
+	r := httptest.NewRequest("GET", "/search", nil)
+	signRequest(r, "", "bob", "hmacsecret", time.Now())
+	if status, p := authenticate(chain, r); status != http.StatusOK || p == nil || p.ID != "bob" {
+		t.Errorf("signed request: got status %d and principal %+v, want bob", status, p)
+	}
+
+	// Invalid credentials of one kind aren't made up for by another.
+	r = httptest.NewRequest("GET", "/search", nil)
+	r.Header.Set(APIKeyHeader, "wrong")
+	signRequest(r, "", "bob", "hmacsecret", time.Now().Add(-time.Second))
+	if status, _ := authenticate(chain, r); status != http.StatusUnauthorized {
+		t.Errorf("request with an invalid API key: got status %d, want %d", status, http.StatusUnauthorized)
+	}
+}
+
+func TestMetricID(t *testing.T) {
+	tests := []struct {
+		p    Principal
+		want string
+	}{
+		{Principal{ID: "alice", Method: MethodAPIKey}, "alice"},
+		{Principal{ID: "bob", Method: MethodHMAC}, "bob"},
+		// JWT subjects are unbounded, so aren't tagged.
+		{Principal{ID: "carol", Method: MethodJWT}, MethodJWT},
+	}
+	for _, tt := range tests {
+		if got := tt.p.metricID(); got != tt.want {
+			t.Errorf("metricID of %+v = %q, want %q", tt.p, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/orijtech/media-search/config"
+	"github.com/orijtech/media-search/secrets"
+)
+
+// Config selects the kinds of credentials accepted. Authentication
+// is disabled unless at least one of them is configured.
+type Config struct {
+	APIKeys  secrets.Config
+	HMACKeys secrets.Config
+	// HMACMaxSkew bounds how far off the timestamp of a signed request may be.
+	HMACMaxSkew time.Duration
+
+	// JWKSFile, if set, enables JWT bearer tokens signed by its keys.
+	JWKSFile           string
+	JWTIssuer          string
+	JWTAudience        string
+	JWKSReloadInterval time.Duration
+}
+
+// Fields returns the settings of c, to be loaded by a config.Loader.
+func (c *Config) Fields() []*config.Field {
+	fields := append(c.APIKeys.Fields(), c.HMACKeys.Fields()...)
+	return append(fields, []*config.Field{
+		{Name: "auth-hmac-max-skew", Env: "MEDIA_SEARCH_AUTH_HMAC_MAX_SKEW", Usage: "how far off the timestamp of an HMAC signed request may be", Value: &c.HMACMaxSkew},
+		{Name: "auth-jwks-file", Env: "MEDIA_SEARCH_AUTH_JWKS_FILE", Usage: "the path to the JWKS that JWT bearer tokens are verified against, enabling them", Value: &c.JWKSFile},
+		{Name: "auth-jwt-issuer", Env: "MEDIA_SEARCH_AUTH_JWT_ISSUER", Usage: "if set, the issuer that JWT bearer tokens must have", Value: &c.JWTIssuer},
+		{Name: "auth-jwt-audience", Env: "MEDIA_SEARCH_AUTH_JWT_AUDIENCE", Usage: "if set, the audience that JWT bearer tokens must be meant for", Value: &c.JWTAudience},
+		{Name: "auth-jwks-reload-interval", Env: "MEDIA_SEARCH_AUTH_JWKS_RELOAD_INTERVAL", Usage: "how often the JWKS is reloaded", Value: &c.JWKSReloadInterval},
+	}...)
+}
+
+// Validate returns an error if any of the durations isn't positive.
+func (c *Config) Validate() error {
+	if err := c.APIKeys.Validate(); err != nil {
+		return err
+	}
+	if err := c.HMACKeys.Validate(); err != nil {
+		return err
+	}
+	if err := config.CheckPositive("auth-hmac-max-skew", c.HMACMaxSkew); err != nil {
+		return err
+	}
+	if c.JWKSFile == "" && (c.JWTIssuer != "" || c.JWTAudience != "") {
+		return errors.New("config: auth-jwt-issuer and auth-jwt-audience require auth-jwks-file")
+	}
+	return config.CheckPositive("auth-jwks-reload-interval", c.JWKSReloadInterval)
+}
+
+// Load returns the authenticators configured, reloading their keys
+// until ctx is done. The Chain is empty if none is configured.
+func (c *Config) Load(ctx context.Context) (Chain, error) {
+	var chain Chain
+	if c.APIKeys.Configured() {
+		keys, err := c.APIKeys.NewKeyring()
+		if err != nil {
+			return nil, err
+		}
+		go keys.Watch(ctx, c.APIKeys.ReloadInterval)
+		chain = append(chain, APIKeys(keys))
+	}
+	if c.HMACKeys.Configured() {
+		keys, err := c.HMACKeys.NewKeyring()
+		if err != nil {
+			return nil, err
+		}
+		go keys.Watch(ctx, c.HMACKeys.ReloadInterval)
+		chain = append(chain, HMAC(keys, c.HMACMaxSkew))
+	}
+	if c.JWKSFile != "" {
+		j, err := NewJWT(c.JWKSFile, c.JWTIssuer, c.JWTAudience)
+		if err != nil {
+			return nil, err
+		}
+		go j.Watch(ctx, c.JWKSReloadInterval)
+		chain = append(chain, j)
+	}
+	return chain, nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// The gRPC metadata that the principal is propagated to the backends in.
+const (
+	PrincipalMetadata = "x-media-search-principal"
+	MethodMetadata    = "x-media-search-auth-method"
+)
+
+// UnaryClientInterceptor propagates the principal of the context of every call, if any.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if p := FromContext(ctx); p != nil {
+			ctx = metadata.AppendToOutgoingContext(ctx, PrincipalMetadata, p.ID, MethodMetadata, p.Method)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// UnaryServerInterceptor adds the principal propagated by UnaryClientInterceptor
+// to the context of every call, see NewContext. The principal is taken on
+// trust, so the server must only be reachable by the frontend, e.g. over
+// mutual TLS.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			ids, methods := md.Get(PrincipalMetadata), md.Get(MethodMetadata)
+			if len(ids) > 0 && len(methods) > 0 {
+				ctx = NewContext(ctx, &Principal{ID: ids[0], Method: methods[0]})
+			}
+		}
+		return handler(ctx, req)
+	}
+}
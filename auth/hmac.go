@@ -0,0 +1,166 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/orijtech/media-search/secrets"
+)
+
+// The headers of a request signed with HMAC.
+const (
+	HMACKeyIDHeader     = "X-Auth-Key-Id"
+	HMACTimestampHeader = "X-Auth-Timestamp"
+	HMACSignatureHeader = "X-Auth-Signature"
+)
+
+// MaxSignedBodyBytes is the largest body of a request signed with HMAC.
+// The body is read to be hashed before the request is authenticated,
+// so it is capped not to let anyone make the frontend buffer a huge one.
+const MaxSignedBodyBytes = 64 << 10
+
+// HMAC returns an Authenticator of requests signed with the secrets of keys,
+// each of the form id:secret, whose id is the principal. A request is signed
+// by setting HMACKeyIDHeader to the id, HMACTimestampHeader to the current
+// Unix time in seconds, and HMACSignatureHeader to the hex encoded
+// HMAC-SHA256 of what StringToSign returns for it under the secret.
+// The timestamp must be within maxSkew of the time the request is received.
+//
+// Each signature is only accepted once: it is remembered until its timestamp
+// is more than maxSkew old, and thus rejected anyway, so that a captured
+// request can't be replayed. Identical requests must therefore be signed at
+// least a second apart. The signatures are remembered by each Authenticator,
+// so across replicas a request may still be replayed once per replica.
+func HMAC(keys *secrets.Keyring, maxSkew time.Duration) Authenticator {
+	return &hmacAuth{keys: keys, maxSkew: maxSkew, seen: make(map[string]time.Time)}
+}
+
+type hmacAuth struct {
+	keys    *secrets.Keyring
+	maxSkew time.Duration
+
+	mu sync.Mutex
+	// seen are the signatures accepted, by when their timestamp expires.
+	seen      map[string]time.Time
+	lastSweep time.Time
+}
+
+var (
+	errInvalidSignature  = errors.New("auth: invalid signature")
+	errReplayedSignature = errors.New("auth: replayed signature")
+)
+
+func (ha *hmacAuth) Authenticate(r *http.Request) (*Principal, error) {
+	keyID := r.Header.Get(HMACKeyIDHeader)
+	if keyID == "" {
+		return nil, ErrNoCredentials
+	}
+	signature, err := hex.DecodeString(r.Header.Get(HMACSignatureHeader))
+	if err != nil || len(signature) == 0 {
+		return nil, errInvalidSignature
+	}
+	timestamp := r.Header.Get(HMACTimestampHeader)
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("auth: invalid %s %q", HMACTimestampHeader, timestamp)
+	}
+	if skew := time.Since(time.Unix(unix, 0)); skew > ha.maxSkew || skew < -ha.maxSkew {
+		return nil, fmt.Errorf("auth: %s is more than %v off", HMACTimestampHeader, ha.maxSkew)
+	}
+
+	var secret string
+	for _, s := range ha.keys.Keys() {
+		if id, value := splitID(s); id == keyID {
+			secret = value
+			break
+		}
+	}
+	if secret == "" {
+		return nil, errInvalidSignature
+	}
+
+	toSign, err := StringToSign(r, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(toSign))
+	if !hmac.Equal(mac.Sum(nil), signature) {
+		return nil, errInvalidSignature
+	}
+	if !ha.firstUse(signature, time.Unix(unix, 0).Add(ha.maxSkew)) {
+		return nil, errReplayedSignature
+	}
+	return &Principal{ID: keyID, Method: MethodHMAC}, nil
+}
+
+// firstUse records signature until expiry, reporting
+// whether it wasn't already, i.e. isn't a replay.
+func (ha *hmacAuth) firstUse(signature []byte, expiry time.Time) bool {
+	now := time.Now()
+	ha.mu.Lock()
+	defer ha.mu.Unlock()
+
+	if now.Sub(ha.lastSweep) >= ha.maxSkew {
+		for sig, exp := range ha.seen {
+			if now.After(exp) {
+				delete(ha.seen, sig)
+			}
+		}
+		ha.lastSweep = now
+	}
+	key := string(signature)
+	if exp, ok := ha.seen[key]; ok && !now.After(exp) {
+		return false
+	}
+	ha.seen[key] = expiry
+	return true
+}
+
+// StringToSign returns what is signed for r with HMAC, which is
+//
+//	method + "\n" + request URI + "\n" + timestamp + "\n" + hex(SHA-256(body))
+//
+// It leaves the body of r to be read again, and fails
+// if it is larger than MaxSignedBodyBytes.
+func StringToSign(r *http.Request, timestamp string) (string, error) {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, MaxSignedBodyBytes)); err != nil {
+			r.Body.Close()
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return "", fmt.Errorf("auth: the signed body is larger than %d bytes", MaxSignedBodyBytes)
+			}
+			return "", err
+		}
+		r.Body.Close()
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	sum := sha256.Sum256(body)
+	return r.Method + "\n" + r.URL.RequestURI() + "\n" + timestamp + "\n" + hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,150 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+// jwtAlgorithms are the signature algorithms accepted,
+// all of them asymmetric for the JWKS to hold public keys.
+var jwtAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.EdDSA,
+}
+
+// JWT authenticates requests carrying a JWT as their bearer token,
+// whose principal is its subject. The token must be signed by a key
+// of the JWKS file, found by its key ID, and must expire.
+type JWT struct {
+	file     string
+	issuer   string
+	audience string
+
+	mu     sync.RWMutex
+	loaded []byte
+	keys   *jose.JSONWebKeySet
+}
+
+var _ Authenticator = (*JWT)(nil)
+
+// NewJWT returns a JWT verifying tokens against the keys in jwksFile. If
+// issuer or audience is set, the token must have been issued by issuer
+// or be meant for audience respectively.
+func NewJWT(jwksFile, issuer, audience string) (*JWT, error) {
+	j := &JWT{file: jwksFile, issuer: issuer, audience: audience}
+	if err := j.Reload(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Reload reloads the JWKS file if it has changed. If it fails
+// to load, the current keys are kept.
+func (j *JWT) Reload() error {
+	blob, err := ioutil.ReadFile(j.file)
+	if err != nil {
+		return fmt.Errorf("auth: %v", err)
+	}
+	j.mu.RLock()
+	unchanged := bytes.Equal(blob, j.loaded)
+	j.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	keys := new(jose.JSONWebKeySet)
+	if err := json.Unmarshal(blob, keys); err != nil {
+		return fmt.Errorf("auth: parsing the JWKS %s: %v", j.file, err)
+	}
+	if len(keys.Keys) == 0 {
+		return fmt.Errorf("auth: no keys in the JWKS %s", j.file)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.loaded != nil {
+		slog.Info("Reloaded the JWKS", "file", j.file, "keys", len(keys.Keys))
+	}
+	j.loaded, j.keys = blob, keys
+	return nil
+}
+
+// Watch reloads the JWKS file every interval until ctx is done.
+// A failed reload is only logged.
+func (j *JWT) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := j.Reload(); err != nil {
+			slog.ErrorContext(ctx, "Reloading the JWKS error, keeping the current keys", "err", err)
+		}
+	}
+}
+
+var errNoExpiry = errors.New("auth: the token doesn't expire")
+
+func (j *JWT) Authenticate(r *http.Request) (*Principal, error) {
+	authorization := r.Header.Get("Authorization")
+	if len(authorization) < 7 || !strings.EqualFold(authorization[:7], "Bearer ") {
+		return nil, ErrNoCredentials
+	}
+	token, err := jwt.ParseSigned(strings.TrimSpace(authorization[7:]), jwtAlgorithms)
+	if err != nil {
+		return nil, fmt.Errorf("auth: %v", err)
+	}
+
+	j.mu.RLock()
+	keys := j.keys
+	j.mu.RUnlock()
+	var claims jwt.Claims
+	if err := token.Claims(keys, &claims); err != nil {
+		return nil, fmt.Errorf("auth: %v", err)
+	}
+	if claims.Expiry == nil {
+		return nil, errNoExpiry
+	}
+	expected := jwt.Expected{Issuer: j.issuer, Time: time.Now()}
+	if j.audience != "" {
+		expected.AnyAudience = jwt.Audience{j.audience}
+	}
+	if err := claims.ValidateWithLeeway(expected, 30*time.Second); err != nil {
+		return nil, fmt.Errorf("auth: %v", err)
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("auth: the token has no subject")
+	}
+	return &Principal{ID: claims.Subject, Method: MethodJWT}, nil
+}
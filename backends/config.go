@@ -0,0 +1,153 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/orijtech/media-search/certs"
+	"github.com/orijtech/media-search/config"
+	"github.com/orijtech/media-search/graceful"
+	"github.com/orijtech/media-search/rpc"
+	"github.com/orijtech/media-search/secrets"
+	"github.com/orijtech/media-search/telemetry"
+)
+
+// backendsConfig is the configuration of the search and ID backends.
+type backendsConfig struct {
+	// HTTP serves the backends over HTTP instead of gRPC.
+	HTTP bool
+	Port int
+	// HealthPort serves the health checks when serving gRPC.
+	HealthPort int
+
+	// If HedgeDelay is positive, YouTube API calls taking longer are hedged.
+	HedgeDelay     time.Duration
+	MaxHedgedCalls int
+
+	YouTubeAPIKeys secrets.Config
+	// YouTubeAPIKeyDailyQuota, if positive, is the number
+	// of quota units each key may use per day.
+	YouTubeAPIKeyDailyQuota int
+
+	DetailerURL string
+
+	// IDScheme is the scheme of the IDs of the GenID requests that don't
+	// ask for one and IDNode the node ID of the Snowflake IDs, which must
+	// differ between the replicas.
+	IDScheme string
+	IDNode   int
+
+	TLS       certs.Config
+	Telemetry telemetry.Config
+	Shutdown  *graceful.Shutdown
+}
+
+func defaultConfig() *backendsConfig {
+	return &backendsConfig{
+		Port:           8899,
+		HealthPort:     8898,
+		MaxHedgedCalls: 2,
+		DetailerURL:    "http://localhost:9944",
+		IDScheme:       rpc.SchemeXID,
+		YouTubeAPIKeys: secrets.Config{
+			Name:           "youtube-api-key",
+			Env:            "YOUTUBE_API_KEY",
+			ReloadInterval: time.Minute,
+		},
+		TLS: certs.Config{
+			DevDir:         "dev-certs",
+			ReloadInterval: time.Minute,
+		},
+		Telemetry: telemetry.Config{
+			ServiceName:         "media-search-backends",
+			PrometheusAddr:      ":9988",
+			PrometheusNamespace: "mediasearch",
+			ReportingPeriod:     10 * time.Second,
+			SampleRate:          1e-4,
+		},
+		Shutdown: graceful.New(),
+	}
+}
+
+func (bc *backendsConfig) fields() []*config.Field {
+	return []*config.Field{
+		{Name: "http", Env: "MEDIA_SEARCH_BACKENDS_HTTP", Usage: "if set true, run it as an HTTP server instead of as a gRPC server", Value: &bc.HTTP},
+		{Name: "port", Env: "MEDIA_SEARCH_BACKENDS_PORT", Usage: "the port on which to run the server", Value: &bc.Port},
+		{Name: "health-port", Env: "MEDIA_SEARCH_BACKENDS_HEALTH_PORT", Usage: "the port on which to serve /healthz and /readyz when running as a gRPC server", Value: &bc.HealthPort},
+		{Name: "hedge-delay", Env: "MEDIA_SEARCH_BACKENDS_HEDGE_DELAY", Usage: "if positive, how long to wait on a YouTube API call before hedging it with another", Value: &bc.HedgeDelay},
+		{Name: "max-hedged-calls", Env: "MEDIA_SEARCH_BACKENDS_MAX_HEDGED_CALLS", Usage: "the maximum number of concurrent calls per hedged YouTube API call", Value: &bc.MaxHedgedCalls},
+		{Name: "detailer-url", Env: "YOUTUBE_DETAILS_HTTP_SERVER_URL", Usage: "the URL of the detailer", Value: &bc.DetailerURL},
+		{Name: "id-scheme", Env: "MEDIA_SEARCH_BACKENDS_ID_SCHEME", Usage: fmt.Sprintf("the scheme of the IDs generated unless another is requested, one of %v", rpc.IDSchemes), Value: &bc.IDScheme},
+		{Name: "id-node", Env: "MEDIA_SEARCH_BACKENDS_ID_NODE", Usage: fmt.Sprintf("the node ID, in [0, %d] and unique to each replica, of the snowflake IDs", rpc.MaxNodeID), Value: &bc.IDNode},
+		{Name: "youtube-api-key-daily-quota", Env: "MEDIA_SEARCH_YOUTUBE_API_KEY_DAILY_QUOTA", Usage: "if positive, the quota units each YouTube API key may use per day before it is skipped until the quota resets", Value: &bc.YouTubeAPIKeyDailyQuota},
+	}
+}
+
+func (bc *backendsConfig) validate() error {
+	if err := config.CheckPort("port", bc.Port); err != nil {
+		return err
+	}
+	if err := config.CheckPort("health-port", bc.HealthPort); err != nil {
+		return err
+	}
+	if !bc.HTTP && bc.HealthPort == bc.Port {
+		return fmt.Errorf("config: health-port must differ from port %d when serving gRPC", bc.Port)
+	}
+	if bc.HedgeDelay < 0 || bc.MaxHedgedCalls < 1 {
+		return fmt.Errorf("config: hedge-delay %v must not be negative and max-hedged-calls %d must be positive", bc.HedgeDelay, bc.MaxHedgedCalls)
+	}
+	if err := rpc.CheckIDScheme(bc.IDScheme); err != nil {
+		return fmt.Errorf("config: id-scheme: %v", err)
+	}
+	if bc.IDNode < 0 || bc.IDNode > rpc.MaxNodeID {
+		return fmt.Errorf("config: id-node %d is not in [0, %d]", bc.IDNode, rpc.MaxNodeID)
+	}
+	return config.CheckURL("detailer-url", bc.DetailerURL)
+}
+
+// loadConfig loads the configuration from the command line, the
+// environment and the -config file, and exits if it is invalid or
+// if it was only asked to be printed.
+func loadConfig() *backendsConfig {
+	bc := defaultConfig()
+	loader := config.NewLoader(flag.CommandLine)
+	loader.Add(bc.fields()...)
+	loader.Add(bc.YouTubeAPIKeys.Fields()...)
+	loader.Add(bc.TLS.Fields()...)
+	loader.Add(bc.Telemetry.Fields()...)
+	loader.Add(bc.Shutdown.Fields()...)
+	loader.Validate(bc.validate)
+	loader.Validate(bc.YouTubeAPIKeys.Validate)
+	loader.Validate(bc.TLS.Validate)
+	loader.Validate(bc.Telemetry.Validate)
+	loader.Validate(bc.Shutdown.Validate)
+	flag.Parse()
+
+	if err := loader.Load(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if loader.PrintRequested() {
+		if err := loader.Print(os.Stdout); err != nil {
+			log.Fatalf("Failed to print the configuration: %v", err)
+		}
+		os.Exit(0)
+	}
+	return bc
+}
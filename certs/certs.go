@@ -0,0 +1,264 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package certs loads the TLS certificate of a service and the CA that
+// its peers' certificates are verified against, reloading both as they
+// are rotated, for serving and dialing over TLS and mutual TLS.
+package certs
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/orijtech/media-search/config"
+)
+
+// Config locates the certificates. The zero value disables TLS.
+type Config struct {
+	// CertFile and KeyFile, if set, hold the PEM encoded certificate of
+	// the service, which it serves and presents to its peers.
+	CertFile string
+	KeyFile  string
+	// CAFile, if set, holds the PEM encoded CA certificates that the
+	// certificates of the peers are verified against, enabling mutual TLS.
+	// Otherwise the servers are verified against the system's CAs.
+	CAFile string
+
+	// Dev, unless CertFile is set, generates a local CA in DevDir
+	// along with a certificate it signed, for mutual TLS on localhost.
+	Dev    bool
+	DevDir string
+
+	ReloadInterval time.Duration
+}
+
+// Fields returns the settings of c, to be loaded by a config.Loader.
+func (c *Config) Fields() []*config.Field {
+	return []*config.Field{
+		{Name: "tls-cert-file", Env: "MEDIA_SEARCH_TLS_CERT_FILE", Usage: "the path to the PEM encoded TLS certificate, enabling TLS", Value: &c.CertFile},
+		{Name: "tls-key-file", Env: "MEDIA_SEARCH_TLS_KEY_FILE", Usage: "the path to the PEM encoded key of the TLS certificate", Value: &c.KeyFile},
+		{Name: "tls-ca-file", Env: "MEDIA_SEARCH_TLS_CA_FILE", Usage: "the path to the PEM encoded CA certificates that peers are verified against, enabling mutual TLS", Value: &c.CAFile},
+		{Name: "tls-dev", Env: "MEDIA_SEARCH_TLS_DEV", Usage: "if set, generate a local CA and certificates for mutual TLS on localhost", Value: &c.Dev},
+		{Name: "tls-dev-dir", Env: "MEDIA_SEARCH_TLS_DEV_DIR", Usage: "the directory in which -tls-dev keeps the local CA and certificates", Value: &c.DevDir},
+		{Name: "tls-reload-interval", Env: "MEDIA_SEARCH_TLS_RELOAD_INTERVAL", Usage: "how often the TLS certificates are reloaded", Value: &c.ReloadInterval},
+	}
+}
+
+// Validate returns an error if the certificate is missing its key or
+// vice versa, or if the reload interval isn't positive.
+func (c *Config) Validate() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return errors.New("config: tls-cert-file and tls-key-file must be set together")
+	}
+	if c.CAFile != "" && c.CertFile == "" && !c.Dev {
+		return errors.New("config: tls-ca-file requires tls-cert-file, or tls-dev")
+	}
+	if c.Dev && c.DevDir == "" {
+		return errors.New("config: tls-dev-dir is blank")
+	}
+	return config.CheckPositive("tls-reload-interval", c.ReloadInterval)
+}
+
+// Load loads the certificates configured, generating them first in dev
+// mode for the service name. It returns nil if TLS isn't enabled, which
+// the methods of *Certs handle by serving and dialing without TLS.
+func (c *Config) Load(name string) (*Certs, error) {
+	files := *c
+	if files.CertFile == "" {
+		if !files.Dev {
+			return nil, nil
+		}
+		var err error
+		if files.CertFile, files.KeyFile, files.CAFile, err = generateDev(files.DevDir, name); err != nil {
+			return nil, fmt.Errorf("certs: generating the dev certificates: %v", err)
+		}
+	}
+
+	crt := &Certs{certFile: files.CertFile, keyFile: files.KeyFile, caFile: files.CAFile}
+	if err := crt.Reload(); err != nil {
+		return nil, err
+	}
+	return crt, nil
+}
+
+// Certs holds the certificate of a service and the CA of its peers.
+type Certs struct {
+	certFile, keyFile, caFile string
+
+	mu sync.RWMutex
+	// loaded holds what the files held when last loaded, to tell when they change.
+	loaded []byte
+	cert   *tls.Certificate
+	// roots is nil if there is no CA file, in which case
+	// servers are verified against the system's CAs.
+	roots *x509.CertPool
+}
+
+// Reload reloads the certificates if their files have changed. If any
+// of the files fails to load, the current certificates are kept.
+func (crt *Certs) Reload() error {
+	certPEM, err := ioutil.ReadFile(crt.certFile)
+	if err != nil {
+		return fmt.Errorf("certs: %v", err)
+	}
+	keyPEM, err := ioutil.ReadFile(crt.keyFile)
+	if err != nil {
+		return fmt.Errorf("certs: %v", err)
+	}
+	var caPEM []byte
+	if crt.caFile != "" {
+		if caPEM, err = ioutil.ReadFile(crt.caFile); err != nil {
+			return fmt.Errorf("certs: %v", err)
+		}
+	}
+	loaded := bytes.Join([][]byte{certPEM, keyPEM, caPEM}, nil)
+
+	crt.mu.RLock()
+	unchanged := bytes.Equal(loaded, crt.loaded)
+	crt.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("certs: loading %s and %s: %v", crt.certFile, crt.keyFile, err)
+	}
+	var roots *x509.CertPool
+	if caPEM != nil {
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(caPEM) {
+			return fmt.Errorf("certs: no CA certificates found in %s", crt.caFile)
+		}
+	}
+
+	crt.mu.Lock()
+	defer crt.mu.Unlock()
+	if crt.loaded != nil {
+		slog.Info("Reloaded the TLS certificates", "cert_file", crt.certFile, "ca_file", crt.caFile)
+	}
+	crt.loaded, crt.cert, crt.roots = loaded, &cert, roots
+	return nil
+}
+
+// Watch reloads the certificates every interval until ctx is done.
+// A failed reload is only logged.
+func (crt *Certs) Watch(ctx context.Context, interval time.Duration) {
+	if crt == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := crt.Reload(); err != nil {
+			slog.ErrorContext(ctx, "Reloading the TLS certificates error, keeping the current ones", "err", err)
+		}
+	}
+}
+
+// Mutual reports whether the certificates of peers are verified, i.e. whether a CA is configured.
+func (crt *Certs) Mutual() bool {
+	return crt != nil && crt.caFile != ""
+}
+
+func (crt *Certs) current() (*tls.Certificate, *x509.CertPool) {
+	crt.mu.RLock()
+	defer crt.mu.RUnlock()
+	return crt.cert, crt.roots
+}
+
+// ServerTLS returns the configuration of a TLS server, or nil if crt is.
+// With mutual TLS, clientAuth is either tls.RequestClientCert, verifying
+// the client certificates given, or tls.RequireAnyClientCert, also
+// rejecting clients without one. Otherwise it is ignored.
+//
+// The client certificates are verified against the CA in effect at
+// the time of the handshake, for the CA to be rotated while serving.
+func (crt *Certs) ServerTLS(clientAuth tls.ClientAuthType) *tls.Config {
+	if crt == nil {
+		return nil
+	}
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert, _ := crt.current()
+			return cert, nil
+		},
+	}
+	if crt.Mutual() {
+		cfg.ClientAuth = clientAuth
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return nil
+			}
+			_, roots := crt.current()
+			return verify(cs.PeerCertificates, roots, "", x509.ExtKeyUsageClientAuth)
+		}
+	}
+	return cfg
+}
+
+// ClientTLS returns the configuration of a TLS client, or nil if crt is.
+// It presents the certificate of the service and verifies that of the
+// server against the CA in effect at the time of the handshake.
+func (crt *Certs) ClientTLS() *tls.Config {
+	if crt == nil {
+		return nil
+	}
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := crt.current()
+			return cert, nil
+		},
+		// The default verification is only skipped for VerifyConnection
+		// to verify against the current CA rather than a fixed one.
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			_, roots := crt.current()
+			return verify(cs.PeerCertificates, roots, cs.ServerName, x509.ExtKeyUsageServerAuth)
+		},
+	}
+}
+
+// verify verifies chain against roots, the system's CAs if nil.
+func verify(chain []*x509.Certificate, roots *x509.CertPool, dnsName string, usage x509.ExtKeyUsage) error {
+	if len(chain) == 0 {
+		return errors.New("certs: no peer certificate")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		DNSName:       dnsName,
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	})
+	return err
+}
@@ -0,0 +1,203 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certs
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// generateDev returns the files of a certificate for the service name,
+// signed by the local CA in dir, which it creates if it doesn't exist.
+// Every service started with the same dir thus trusts the others.
+func generateDev(dir, name string) (certFile, keyFile, caFile string, err error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", "", "", err
+	}
+	caCert, caKey, err := loadOrCreateCA(filepath.Join(dir, "ca-and-key.pem"))
+	if err != nil {
+		return "", "", "", err
+	}
+	caFile = filepath.Join(dir, "ca.pem")
+	if err := writeFile(caFile, pemBlock("CERTIFICATE", caCert.Raw), 0644); err != nil {
+		return "", "", "", err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", "", err
+	}
+	template, err := newTemplate(name)
+	if err != nil {
+		return "", "", "", err
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	// Every service is both a server and a client of the others.
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	template.DNSNames = []string{"localhost", name}
+	if hostname, err := os.Hostname(); err == nil {
+		template.DNSNames = append(template.DNSNames, hostname)
+	}
+	template.IPAddresses = []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, key.Public(), caKey)
+	if err != nil {
+		return "", "", "", err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	certFile = filepath.Join(dir, name+".pem")
+	keyFile = filepath.Join(dir, name+"-key.pem")
+	if err := writeFile(keyFile, pemBlock("PRIVATE KEY", keyDER), 0600); err != nil {
+		return "", "", "", err
+	}
+	if err := writeFile(certFile, pemBlock("CERTIFICATE", der), 0644); err != nil {
+		return "", "", "", err
+	}
+	return certFile, keyFile, caFile, nil
+}
+
+// loadOrCreateCA loads the CA certificate and key kept together in path, so
+// that the services started at once all end up with the first one created.
+func loadOrCreateCA(path string) (*x509.Certificate, crypto.Signer, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := createCA(path); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var cert *x509.Certificate
+	var key crypto.Signer
+	for block, rest := pem.Decode(blob); block != nil; block, rest = pem.Decode(rest) {
+		switch block.Type {
+		case "CERTIFICATE":
+			cert, err = x509.ParseCertificate(block.Bytes)
+		case "PRIVATE KEY":
+			var parsed interface{}
+			if parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+				key, _ = parsed.(crypto.Signer)
+			}
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if cert == nil || key == nil {
+		return nil, nil, errors.New(path + " lacks the CA certificate or key")
+	}
+	return cert, key, nil
+}
+
+func createCA(path string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	template, err := newTemplate("media-search dev CA")
+	if err != nil {
+		return err
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	template.NotAfter = template.NotBefore.AddDate(10, 0, 0)
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	// Link the complete file into place, which fails if another
+	// service did so first, in which case its CA is used instead.
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".ca-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(append(pemBlock("CERTIFICATE", der), pemBlock("PRIVATE KEY", keyDER)...))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Link(tmp.Name(), path); err != nil && !os.IsExist(err) {
+		return err
+	}
+	return nil
+}
+
+func newTemplate(commonName string) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{Organization: []string{"media-search"}, CommonName: commonName},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.AddDate(1, 0, 0),
+	}, nil
+}
+
+func pemBlock(typ string, der []byte) []byte {
+	var buf bytes.Buffer
+	pem.Encode(&buf, &pem.Block{Type: typ, Bytes: der})
+	return buf.Bytes()
+}
+
+// writeFile replaces the file at path with data at once, so
+// that it is never read, or reloaded, while half written.
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), perm)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,81 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certs
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/orijtech/media-search/rpc"
+)
+
+// ListenAndServe serves srv over TLS, see ServerTLS, or without it if crt is nil.
+func (crt *Certs) ListenAndServe(srv *http.Server, clientAuth tls.ClientAuthType) error {
+	if crt == nil {
+		return srv.ListenAndServe()
+	}
+	srv.TLSConfig = crt.ServerTLS(clientAuth)
+	return srv.ListenAndServeTLS("", "")
+}
+
+// RequireClientCert rejects the requests to h made without a client
+// certificate, except for those that exempt returns true for, e.g. the
+// health checks of probes that can't present one. The certificates given
+// are verified during the handshake, see ServerTLS with tls.RequestClientCert.
+// Without mutual TLS it returns h as is.
+func (crt *Certs) RequireClientCert(h http.Handler, exempt func(*http.Request) bool) http.Handler {
+	if !crt.Mutual() {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if (r.TLS == nil || len(r.TLS.PeerCertificates) == 0) && !exempt(r) {
+			rpc.WriteHTTPError(w, rpc.Errorf(rpc.CodeUnauthenticated, "a client certificate is required"))
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// ServerOption returns the option serving gRPC over mutual
+// TLS, or over TLS without a CA, or in the clear if crt is nil.
+func (crt *Certs) ServerOption() grpc.ServerOption {
+	if crt == nil {
+		return grpc.EmptyServerOption{}
+	}
+	return grpc.Creds(credentials.NewTLS(crt.ServerTLS(tls.RequireAnyClientCert)))
+}
+
+// DialOption returns the option dialing gRPC over TLS, see ClientTLS,
+// or in the clear if crt is nil.
+func (crt *Certs) DialOption() grpc.DialOption {
+	if crt == nil {
+		return grpc.WithInsecure()
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(crt.ClientTLS()))
+}
+
+// Transport returns an HTTP transport dialing over TLS, see
+// ClientTLS, or http.DefaultTransport if crt is nil.
+func (crt *Certs) Transport() http.RoundTripper {
+	if crt == nil {
+		return http.DefaultTransport
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = crt.ClientTLS()
+	return transport
+}
@@ -18,17 +18,36 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/orijtech/otils"
-	"github.com/orijtech/youtube"
+
+	v1 "github.com/orijtech/media-search/api/v1"
 )
 
 func main() {
+	parse := flag.String("parse", "", "if set, the file of a /v1/search response to print the conformance form of, see clients/conformance")
+	flag.Parse()
+	if *parse != "" {
+		blob, err := ioutil.ReadFile(*parse)
+		if err != nil {
+			log.Fatalf("Failed to read the response: %v", err)
+		}
+		resp, err := parseSearchResponse(blob)
+		if err != nil {
+			log.Fatalf("Failed to parse the response: %v", err)
+		}
+		printConformance(os.Stdout, resp)
+		return
+	}
+
 	client := &http.Client{}
 	br := bufio.NewReader(os.Stdin)
 	for {
@@ -43,7 +62,7 @@ func main() {
 		if err != nil {
 			log.Fatalf("Failed to json.Marshal input blob: %v", err)
 		}
-		req, err := http.NewRequest("POST", "http://localhost:9778/search", bytes.NewReader(inBlob))
+		req, err := http.NewRequest("POST", "http://localhost:9778/v1/search", bytes.NewReader(inBlob))
 		if err != nil {
 			log.Fatalf("Failed to build POST request: %v", err)
 		}
@@ -60,24 +79,73 @@ func main() {
 		if err != nil {
 			log.Fatalf("Failed to read res.Body: %v", err)
 		}
-		var pages []*youtube.SearchPage
-		if err := json.Unmarshal(outBlob, &pages); err != nil {
+		resp, err := parseSearchResponse(outBlob)
+		if err != nil {
 			log.Fatalf("Unmarshaling responses: %v", err)
 		}
-		for _, page := range pages {
-			for _, video := range page.Items {
-				if video == nil {
-					continue
-				}
-				snippet := video.Snippet
-				if video.Id.VideoId != "" {
-					fmt.Printf("URL: https://youtu.be/%s\n", video.Id.VideoId)
-				} else if video.Id.ChannelId != "" {
-					fmt.Printf("ChannelURL: https://www.youtube.com/channel/%s\n",
-						video.Id.ChannelId)
-				}
-				fmt.Printf("Title: %s\nDescription: %s\n\n\n", snippet.Title, snippet.Description)
+		for _, item := range resp.Items {
+			fmt.Printf("URL: %s\nTitle: %s\nDescription: %s\n\n\n", item.URL, item.Title, item.Description)
+		}
+		for _, pageErr := range resp.Errors {
+			fmt.Printf("Page %d failed: %s: %s\n", pageErr.Page, pageErr.Code, pageErr.Message)
+		}
+	}
+}
+
+func parseSearchResponse(blob []byte) (*v1.SearchResponse, error) {
+	resp := new(v1.SearchResponse)
+	if err := json.Unmarshal(blob, resp); err != nil {
+		return nil, err
+	}
+	if resp.Version != v1.Version {
+		return nil, fmt.Errorf("unsupported version %q", resp.Version)
+	}
+	return resp, nil
+}
+
+// printConformance prints resp in the form that every client
+// prints a response in, see clients/conformance/README.md.
+func printConformance(w io.Writer, resp *v1.SearchResponse) {
+	fmt.Fprintf(w, "query: %s\n", quote(resp.Query))
+	for _, item := range resp.Items {
+		fmt.Fprintln(w, "item")
+		fmt.Fprintf(w, "kind: %s\n", quote(item.Kind))
+		fmt.Fprintf(w, "id: %s\n", quote(item.ID))
+		fmt.Fprintf(w, "url: %s\n", quote(item.URL))
+		fmt.Fprintf(w, "title: %s\n", quote(item.Title))
+		fmt.Fprintf(w, "description: %s\n", quote(item.Description))
+		publishedAt := "null"
+		if item.PublishedAt != nil {
+			publishedAt = quote(*item.PublishedAt)
+		}
+		fmt.Fprintf(w, "published_at: %s\n", publishedAt)
+		fmt.Fprintf(w, "channel_id: %s\n", quote(item.Channel.ID))
+		fmt.Fprintf(w, "channel_title: %s\n", quote(item.Channel.Title))
+		thumbnail, width, height := "null", "null", "null"
+		for _, th := range []*v1.Thumbnail{item.Thumbnails.High, item.Thumbnails.Medium, item.Thumbnails.Default} {
+			if th == nil {
+				continue
 			}
+			thumbnail = quote(th.URL)
+			if th.Width != nil {
+				width = fmt.Sprint(*th.Width)
+			}
+			if th.Height != nil {
+				height = fmt.Sprint(*th.Height)
+			}
+			break
 		}
+		fmt.Fprintf(w, "thumbnail: %s\nthumbnail_width: %s\nthumbnail_height: %s\n", thumbnail, width, height)
+		fmt.Fprintf(w, "page: %d\n", item.Page)
+	}
+	for _, pageErr := range resp.Errors {
+		fmt.Fprintln(w, "error")
+		fmt.Fprintf(w, "page: %d\ncode: %s\nmessage: %s\n", pageErr.Page, quote(pageErr.Code), quote(pageErr.Message))
 	}
 }
+
+var quoter = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+func quote(s string) string {
+	return `"` + quoter.Replace(s) + `"`
+}
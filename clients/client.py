@@ -16,28 +16,84 @@ See the License for the specific language governing permissions and
 limitations under the License.
 """
 
-import requests
+import json
+import sys
 
 def main():
+  if len(sys.argv) == 3 and sys.argv[1] == '--parse':
+    # Prints the conformance form of a /v1/search response, see clients/conformance.
+    with open(sys.argv[2], encoding='utf-8') as f:
+      printConformance(parseSearchResponse(f.read()))
+    return
+
   while True:
     query = input('Content to search$ ')
     doSearch(query)
 
 def doSearch(query):
-    res = requests.post('http://localhost:9778/search', json={'keywords': query})
-    pages = res.json()
-    for page in pages:
-      items = page['items']
-      for i, item in enumerate(items):
-        id = item['id']
-        if 'videoId' in id:
-          print('URL: https://youtu.be/{videoId}'.format(**item['id']))
-        elif 'channelId' in id:
-          print('ChannelURL: https://www.youtube.com/channel/{channelId}'.format(**item['id']))
-
-        snippet = item['snippet']
-        snippet.setdefault('description', 'Unknown')
-        print('Title: {title}\nDescription: {description}\n\n'.format(**snippet))
+    # Imported here for --parse not to need it.
+    import requests
+
+    res = requests.post('http://localhost:9778/v1/search', json={'keywords': query})
+    if not res.ok:
+      # Failures are of the form {"error": {"code": ..., "message": ...}}
+      print('Error {code}: {message}\n'.format(**res.json()['error']))
+      return
+
+    response = parseSearchResponse(res.text)
+    for item in response['items']:
+      print('URL: {url}\nTitle: {title}\nDescription: {description}\n\n'.format(**item))
+    for error in response['errors']:
+      print('Page {page} failed: {code}: {message}'.format(**error))
+
+def parseSearchResponse(text):
+    response = json.loads(text)
+    if response.get('version') != 'v1':
+      raise ValueError('unsupported version {!r}'.format(response.get('version')))
+    return response
+
+def quote(s):
+    for old, new in (('\\', '\\\\'), ('"', '\\"'), ('\n', '\\n'), ('\r', '\\r'), ('\t', '\\t')):
+      s = s.replace(old, new)
+    return '"' + s + '"'
+
+def number(n):
+    return 'null' if n is None else str(n)
+
+def printConformance(response):
+    """Prints response in the form that every client prints a response in, see clients/conformance/README.md."""
+    lines = ['query: ' + quote(response['query'])]
+    for item in response['items']:
+      thumbnail, width, height = 'null', 'null', 'null'
+      thumbnails = item['thumbnails']
+      for th in (thumbnails['high'], thumbnails['medium'], thumbnails['default']):
+        if th is not None:
+          thumbnail, width, height = quote(th['url']), number(th['width']), number(th['height'])
+          break
+      publishedAt = item['published_at']
+      lines += [
+        'item',
+        'kind: ' + quote(item['kind']),
+        'id: ' + quote(item['id']),
+        'url: ' + quote(item['url']),
+        'title: ' + quote(item['title']),
+        'description: ' + quote(item['description']),
+        'published_at: ' + ('null' if publishedAt is None else quote(publishedAt)),
+        'channel_id: ' + quote(item['channel']['id']),
+        'channel_title: ' + quote(item['channel']['title']),
+        'thumbnail: ' + thumbnail,
+        'thumbnail_width: ' + width,
+        'thumbnail_height: ' + height,
+        'page: ' + str(item['page']),
+      ]
+    for error in response['errors']:
+      lines += [
+        'error',
+        'page: ' + str(error['page']),
+        'code: ' + quote(error['code']),
+        'message: ' + quote(error['message']),
+      ]
+    sys.stdout.write('\n'.join(lines) + '\n')
 
 if __name__ == '__main__':
   main()
@@ -0,0 +1,48 @@
+#!/bin/sh
+
+# Copyright 2018, OpenCensus Authors
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Checks that the Go, Python and Javascript clients parse every
+# /v1/search response in responses/ as expected/ says, see README.md.
+# Run it from the root of the repository, e.g. with make conformance.
+
+set -u
+
+dir=clients/conformance
+tmp=$(mktemp -d)
+trap 'rm -rf "$tmp"' EXIT
+
+go build -o "$tmp/client" ./clients/client.go || exit 1
+
+failed=0
+for response in "$dir"/responses/*.json; do
+    name=$(basename "$response" .json)
+    for client in go python js; do
+        case $client in
+        go) "$tmp/client" -parse "$response" ;;
+        python) python3 clients/client.py --parse "$response" ;;
+        js) node static/v1.js "$response" ;;
+        esac > "$tmp/$name.$client.txt" 2>&1
+        if ! diff -u "$dir/expected/$name.txt" "$tmp/$name.$client.txt"; then
+            echo "FAIL $name: the $client client differs"
+            failed=1
+        fi
+    done
+done
+
+if [ $failed -ne 0 ]; then
+    exit 1
+fi
+echo "PASS the go, python and js clients parse every response alike"
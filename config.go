@@ -0,0 +1,189 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/orijtech/media-search/auth"
+	"github.com/orijtech/media-search/certs"
+	"github.com/orijtech/media-search/config"
+	"github.com/orijtech/media-search/discovery"
+	"github.com/orijtech/media-search/graceful"
+	"github.com/orijtech/media-search/ratelimit"
+	"github.com/orijtech/media-search/secrets"
+	"github.com/orijtech/media-search/telemetry"
+)
+
+// The values of frontendConfig.GenID.
+const (
+	genIDLocal  = "local"
+	genIDRemote = "remote"
+)
+
+// frontendConfig is the configuration of the frontend.
+type frontendConfig struct {
+	Port int
+	// Search finds the replicas of the gRPC {id, search} services.
+	Search discovery.Config
+	// GenID is where the cache IDs come from: "local", generated in
+	// process, or "remote", from the GenID service of the backends.
+	GenID          string
+	MongoServerURI string
+
+	// TrendingRefreshInterval is how long a cached trending feed is served before
+	// it is refetched, so that every region and category costs at most one
+	// mostPopular lookup per interval regardless of how much traffic we get.
+	TrendingRefreshInterval time.Duration
+	SuggestRefreshInterval  time.Duration
+	// SuggestLookback bounds how far back the search log is read
+	// so that a rebuild doesn't have to scan every query ever made.
+	SuggestLookback time.Duration
+	// SearchLogRetention is how long the search events are kept,
+	// after which MongoDB deletes them by a TTL index on their time.
+	SearchLogRetention time.Duration
+
+	CORS      corsConfig
+	Auth      auth.Config
+	RateLimit ratelimit.Config
+	TLS       certs.Config
+	Telemetry telemetry.Config
+	Shutdown  *graceful.Shutdown
+}
+
+func defaultConfig() *frontendConfig {
+	return &frontendConfig{
+		Port: 9778,
+		Search: discovery.Config{
+			Name:            "search",
+			Addrs:           ":8899",
+			Balancer:        discovery.RoundRobin,
+			ResolveInterval: 30 * time.Second,
+		},
+		GenID:                   genIDLocal,
+		MongoServerURI:          "localhost:27017",
+		TrendingRefreshInterval: time.Hour,
+		SuggestRefreshInterval:  5 * time.Minute,
+		SuggestLookback:         30 * 24 * time.Hour,
+		SearchLogRetention:      30 * 24 * time.Hour,
+		CORS:                    defaultCORSConfig(),
+		Auth: auth.Config{
+			APIKeys:            secrets.Config{Name: "auth-api-key", Env: "MEDIA_SEARCH_AUTH_API_KEYS", ReloadInterval: time.Minute},
+			HMACKeys:           secrets.Config{Name: "auth-hmac-key", Env: "MEDIA_SEARCH_AUTH_HMAC_KEYS", ReloadInterval: time.Minute},
+			HMACMaxSkew:        5 * time.Minute,
+			JWKSReloadInterval: time.Minute,
+		},
+		// A miss costs 100 units of the 10000 of a YouTube API key per day.
+		RateLimit: ratelimit.Config{
+			HitRate:   10,
+			HitBurst:  20,
+			MissRate:  0.2,
+			MissBurst: 5,
+			// Many clients may share an address, e.g. behind a NAT.
+			IPRate:  50,
+			IPBurst: 100,
+		},
+		TLS: certs.Config{
+			DevDir:         "dev-certs",
+			ReloadInterval: time.Minute,
+		},
+		Telemetry: telemetry.Config{
+			ServiceName:             "media-search-frontend",
+			PrometheusAddr:          ":9888",
+			PrometheusNamespace:     "mediasearch",
+			StackdriverMetricPrefix: "mediasearch",
+			ZPagesAddr:              ":7788",
+			ReportingPeriod:         10 * time.Second,
+			// Always sample for demo purposes
+			SampleRate: 1,
+		},
+		Shutdown: graceful.New(),
+	}
+}
+
+func (fc *frontendConfig) fields() []*config.Field {
+	return []*config.Field{
+		{Name: "port", Env: "MEDIA_SEARCH_FRONTEND_PORT", Usage: "the port on which to serve HTTP", Value: &fc.Port},
+		{Name: "genid", Env: "MEDIA_SEARCH_GENID", Usage: "where the cache IDs come from: local, generated in process, or remote, from the backends", Value: &fc.GenID},
+		{Name: "mongo-server-uri", Env: "MEDIA_SEARCH_MONGO_SERVER_URI", Usage: "the host:port of the MongoDB server", Value: &fc.MongoServerURI},
+		{Name: "trending-refresh-interval", Env: "MEDIA_SEARCH_TRENDING_REFRESH_INTERVAL", Usage: "how long a cached trending feed is served before it is refetched", Value: &fc.TrendingRefreshInterval},
+		{Name: "suggest-refresh-interval", Env: "MEDIA_SEARCH_SUGGEST_REFRESH_INTERVAL", Usage: "how often the suggestions are rebuilt", Value: &fc.SuggestRefreshInterval},
+		{Name: "suggest-lookback", Env: "MEDIA_SEARCH_SUGGEST_LOOKBACK", Usage: "how far back the search log is read to rebuild the suggestions", Value: &fc.SuggestLookback},
+		{Name: "search-log-retention", Env: "MEDIA_SEARCH_SEARCH_LOG_RETENTION", Usage: "how long the search events are kept in the search log", Value: &fc.SearchLogRetention},
+	}
+}
+
+func (fc *frontendConfig) validate() error {
+	if err := config.CheckPort("port", fc.Port); err != nil {
+		return err
+	}
+	if fc.GenID != genIDLocal && fc.GenID != genIDRemote {
+		return fmt.Errorf("config: genid: %q is neither %s nor %s", fc.GenID, genIDLocal, genIDRemote)
+	}
+	if fc.MongoServerURI == "" {
+		return errors.New("config: mongo-server-uri is blank")
+	}
+	if err := config.CheckPositive("trending-refresh-interval", fc.TrendingRefreshInterval); err != nil {
+		return err
+	}
+	if err := config.CheckPositive("suggest-refresh-interval", fc.SuggestRefreshInterval); err != nil {
+		return err
+	}
+	if err := config.CheckPositive("suggest-lookback", fc.SuggestLookback); err != nil {
+		return err
+	}
+	return config.CheckPositive("search-log-retention", fc.SearchLogRetention)
+}
+
+// loadConfig loads the configuration from the command line, the
+// environment and the -config file, and exits if it is invalid or
+// if it was only asked to be printed.
+func loadConfig() *frontendConfig {
+	fc := defaultConfig()
+	loader := config.NewLoader(flag.CommandLine)
+	loader.Add(fc.fields()...)
+	loader.Add(fc.Search.Fields()...)
+	loader.Add(fc.CORS.fields()...)
+	loader.Add(fc.Auth.Fields()...)
+	loader.Add(fc.RateLimit.Fields()...)
+	loader.Add(fc.TLS.Fields()...)
+	loader.Add(fc.Telemetry.Fields()...)
+	loader.Add(fc.Shutdown.Fields()...)
+	loader.Validate(fc.validate)
+	loader.Validate(fc.Search.Validate)
+	loader.Validate(fc.CORS.validate)
+	loader.Validate(fc.Auth.Validate)
+	loader.Validate(fc.RateLimit.Validate)
+	loader.Validate(fc.TLS.Validate)
+	loader.Validate(fc.Telemetry.Validate)
+	loader.Validate(fc.Shutdown.Validate)
+	flag.Parse()
+
+	if err := loader.Load(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if loader.PrintRequested() {
+		if err := loader.Print(os.Stdout); err != nil {
+			log.Fatalf("Failed to print the configuration: %v", err)
+		}
+		os.Exit(0)
+	}
+	return fc
+}
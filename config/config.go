@@ -0,0 +1,253 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package config loads the configuration of every binary from, in
+// increasing order of precedence: the defaults, a YAML, TOML or JSON
+// file named by -config, the environment and the command line flags.
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Field is a single setting. Its name is both its flag and its key in the
+// configuration file, and Value points to where it is stored, which holds
+// the default until Load. Value is one of *string, *bool, *int, *float64
+// or *time.Duration.
+type Field struct {
+	Name  string
+	Env   string
+	Usage string
+	Value interface{}
+}
+
+// Loader loads the fields added to it.
+type Loader struct {
+	fs          *flag.FlagSet
+	file        *string
+	printConfig *bool
+
+	fields     []*Field
+	flags      map[string]*flagValue
+	validators []func() error
+}
+
+// NewLoader returns a Loader whose flags are registered on fs, along
+// with -config which names the configuration file, and -print-config.
+func NewLoader(fs *flag.FlagSet) *Loader {
+	return &Loader{
+		fs:          fs,
+		file:        fs.String("config", os.Getenv("MEDIA_SEARCH_CONFIG"), "the path to a YAML, TOML or JSON configuration file (env MEDIA_SEARCH_CONFIG)"),
+		printConfig: fs.Bool("print-config", false, "if set, print the configuration in effect as YAML and exit"),
+		flags:       make(map[string]*flagValue),
+	}
+}
+
+// Add registers the flags of fields, which must be added before the flags are parsed.
+func (l *Loader) Add(fields ...*Field) {
+	for _, f := range fields {
+		if _, ok := f.Value.(*bool); ok {
+			l.flags[f.Name] = &flagValue{isBool: true}
+		} else {
+			l.flags[f.Name] = new(flagValue)
+		}
+		usage := f.Usage
+		if f.Env != "" {
+			usage = fmt.Sprintf("%s (env %s)", usage, f.Env)
+		}
+		l.fs.Var(l.flags[f.Name], f.Name, usage)
+		l.fields = append(l.fields, f)
+	}
+}
+
+// Validate adds a check that Load runs once every field is loaded.
+func (l *Loader) Validate(validate func() error) {
+	l.validators = append(l.validators, validate)
+}
+
+// Load overrides the defaults with the configuration file, then the
+// environment and then the flags that were set, and validates the result.
+// It must be invoked after the flags have been parsed.
+func (l *Loader) Load() error {
+	if path := *l.file; path != "" {
+		if err := l.loadFile(path); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range l.fields {
+		if f.Env == "" {
+			continue
+		}
+		if value, ok := os.LookupEnv(f.Env); ok {
+			if err := set(f.Value, value); err != nil {
+				return fmt.Errorf("config: env %s: %v", f.Env, err)
+			}
+		}
+	}
+
+	var err error
+	l.fs.Visit(func(fl *flag.Flag) {
+		if err != nil {
+			return
+		}
+		for _, f := range l.fields {
+			if f.Name == fl.Name {
+				if serr := set(f.Value, l.flags[f.Name].value); serr != nil {
+					err = fmt.Errorf("config: flag -%s: %v", f.Name, serr)
+				}
+				return
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, validate := range l.validators {
+		if err := validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *Loader) loadFile(path string) error {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: reading config file: %v", err)
+	}
+	settings := make(map[string]interface{})
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(blob, &settings)
+	case ".toml":
+		err = toml.Unmarshal(blob, &settings)
+	case ".json":
+		err = json.Unmarshal(blob, &settings)
+	default:
+		return fmt.Errorf("config: config file %q is neither .yaml, .yml, .toml nor .json", path)
+	}
+	if err != nil {
+		return fmt.Errorf("config: parsing config file %q: %v", path, err)
+	}
+
+	for _, f := range l.fields {
+		value, ok := settings[f.Name]
+		if !ok {
+			continue
+		}
+		delete(settings, f.Name)
+		if err := set(f.Value, fileValue(value)); err != nil {
+			return fmt.Errorf("config: config file %q key %q: %v", path, f.Name, err)
+		}
+	}
+	for key := range settings {
+		return fmt.Errorf("config: config file %q: unknown key %q", path, key)
+	}
+	return nil
+}
+
+// fileValue formats a value decoded from a configuration file for set.
+// JSON decodes every number as a float64, which fmt.Sprint writes in
+// exponent form from 1e+06 on, which strconv.Atoi rejects, hence
+// floats are written out in full.
+func fileValue(value interface{}) string {
+	if f, ok := value.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// PrintRequested reports whether -print-config was set.
+func (l *Loader) PrintRequested() bool {
+	return *l.printConfig
+}
+
+// Print writes the value of every field as YAML, in a form that can be
+// passed back as the configuration file.
+func (l *Loader) Print(w io.Writer) error {
+	settings := make(map[string]interface{}, len(l.fields))
+	for _, f := range l.fields {
+		switch value := f.Value.(type) {
+		case *time.Duration:
+			settings[f.Name] = value.String()
+		case *string:
+			settings[f.Name] = *value
+		case *bool:
+			settings[f.Name] = *value
+		case *int:
+			settings[f.Name] = *value
+		case *float64:
+			settings[f.Name] = *value
+		}
+	}
+	blob, err := yaml.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(blob)
+	return err
+}
+
+func set(field interface{}, value string) (err error) {
+	switch field := field.(type) {
+	case *string:
+		*field = value
+	case *bool:
+		*field, err = strconv.ParseBool(value)
+	case *int:
+		*field, err = strconv.Atoi(value)
+	case *time.Duration:
+		*field, err = time.ParseDuration(value)
+	case *float64:
+		*field, err = strconv.ParseFloat(value, 64)
+	default:
+		err = fmt.Errorf("unhandled field type %T", field)
+	}
+	return err
+}
+
+// flagValue holds the raw value of a flag until Load
+// applies it, so that unset flags override nothing.
+type flagValue struct {
+	isBool bool
+	value  string
+}
+
+func (fv *flagValue) String() string {
+	if fv == nil {
+		return ""
+	}
+	return fv.value
+}
+
+func (fv *flagValue) Set(value string) error {
+	fv.value = value
+	return nil
+}
+
+func (fv *flagValue) IsBoolFlag() bool { return fv.isBool }
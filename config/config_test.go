@@ -0,0 +1,134 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Name     string
+	Enabled  bool
+	Quota    int
+	Rate     float64
+	Interval time.Duration
+}
+
+func (tc *testConfig) fields() []*Field {
+	return []*Field{
+		{Name: "name", Value: &tc.Name},
+		{Name: "enabled", Value: &tc.Enabled},
+		{Name: "quota", Value: &tc.Quota},
+		{Name: "rate", Value: &tc.Rate},
+		{Name: "interval", Value: &tc.Interval},
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	want := testConfig{
+		Name:     "media-search",
+		Enabled:  true,
+		Quota:    10000000,
+		Rate:     0.2,
+		Interval: 90 * time.Second,
+	}
+
+	tests := []struct {
+		file    string
+		content string
+	}{
+		{
+			file: "config.yaml",
+			content: `name: media-search
+enabled: true
+quota: 10000000
+rate: 0.2
+interval: 1m30s
+`,
+		},
+		{
+			file: "config.toml",
+			content: `name = "media-search"
+enabled = true
+quota = 10000000
+rate = 0.2
+interval = "1m30s"
+`,
+		},
+		{
+			file: "config.json",
+			content: `{
+	"name": "media-search",
+	"enabled": true,
+	"quota": 10000000,
+	"rate": 0.2,
+	"interval": "1m30s"
+}`,
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.file)
+		if err := ioutil.WriteFile(path, []byte(tt.content), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		got := new(testConfig)
+		l := NewLoader(flag.NewFlagSet("test", flag.ContinueOnError))
+		l.Add(got.fields()...)
+		if err := l.fs.Parse([]string{"-config", path}); err != nil {
+			t.Fatalf("%s: parsing the flags: %v", tt.file, err)
+		}
+		if err := l.Load(); err != nil {
+			t.Errorf("%s: Load: %v", tt.file, err)
+			continue
+		}
+		if *got != want {
+			t.Errorf("%s: loaded %+v, want %+v", tt.file, *got, want)
+		}
+	}
+}
+
+func TestLoadFileUnknownKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.json")
+	if err := ioutil.WriteFile(path, []byte(`{"quota": 1, "qouta": 2}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	l := NewLoader(flag.NewFlagSet("test", flag.ContinueOnError))
+	l.Add(new(testConfig).fields()...)
+	if err := l.fs.Parse([]string{"-config", path}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Load(); err == nil {
+		t.Error("Load succeeded despite the unknown key \"qouta\"")
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// CheckPort returns an error if port, named name, isn't a valid TCP port.
+func CheckPort(name string, port int) error {
+	if port <= 0 || port > 65535 {
+		return fmt.Errorf("config: %s: port %d is not in [1, 65535]", name, port)
+	}
+	return nil
+}
+
+// CheckAddr returns an error if addr, named name, isn't of the form host:port.
+// The host may be blank, for all the interfaces or for localhost.
+func CheckAddr(name, addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("config: %s: %v", name, err)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		return fmt.Errorf("config: %s: %q has an invalid port", name, addr)
+	}
+	return nil
+}
+
+// CheckOptionalAddr is CheckAddr except that a blank addr, meaning disabled, is valid.
+func CheckOptionalAddr(name, addr string) error {
+	if addr == "" {
+		return nil
+	}
+	return CheckAddr(name, addr)
+}
+
+// CheckURL returns an error if rawURL, named name, isn't an absolute URL.
+func CheckURL(name, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("config: %s: %v", name, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("config: %s: %q is not an absolute URL", name, rawURL)
+	}
+	return nil
+}
+
+// CheckPositive returns an error if d, named name, isn't positive.
+func CheckPositive(name string, d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("config: %s: %v is not positive", name, d)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rs/cors"
+
+	"github.com/orijtech/media-search/auth"
+	"github.com/orijtech/media-search/config"
+)
+
+// corsConfig is the CORS policy of the frontend, which lets the pages of
+// AllowedOrigins call the API. The UI served by the frontend itself is of
+// the same origin, so it needs none.
+type corsConfig struct {
+	// AllowedOrigins are comma separated, each either "*" for every origin or
+	// scheme://host[:port], whose host may start with a "*." wildcard.
+	AllowedOrigins   string
+	AllowedMethods   string
+	AllowedHeaders   string
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache the answer to a preflight request.
+	MaxAge time.Duration
+}
+
+func defaultCORSConfig() corsConfig {
+	return corsConfig{
+		AllowedMethods: "GET,POST",
+		AllowedHeaders: strings.Join([]string{
+			"Content-Type", "Authorization", auth.APIKeyHeader,
+			auth.HMACKeyIDHeader, auth.HMACTimestampHeader, auth.HMACSignatureHeader,
+			"traceparent", "tracestate",
+		}, ","),
+		MaxAge: 10 * time.Minute,
+	}
+}
+
+func (cc *corsConfig) fields() []*config.Field {
+	return []*config.Field{
+		{Name: "cors-allowed-origins", Env: "MEDIA_SEARCH_CORS_ALLOWED_ORIGINS", Usage: "the comma separated origins allowed to call the API, e.g. https://*.example.com, none if blank", Value: &cc.AllowedOrigins},
+		{Name: "cors-allowed-methods", Env: "MEDIA_SEARCH_CORS_ALLOWED_METHODS", Usage: "the comma separated methods that the allowed origins may use", Value: &cc.AllowedMethods},
+		{Name: "cors-allowed-headers", Env: "MEDIA_SEARCH_CORS_ALLOWED_HEADERS", Usage: "the comma separated headers that the allowed origins may send", Value: &cc.AllowedHeaders},
+		{Name: "cors-allow-credentials", Env: "MEDIA_SEARCH_CORS_ALLOW_CREDENTIALS", Usage: "whether the allowed origins may send cookies and HTTP authentication", Value: &cc.AllowCredentials},
+		{Name: "cors-max-age", Env: "MEDIA_SEARCH_CORS_MAX_AGE", Usage: "how long browsers may cache the answer to a preflight request", Value: &cc.MaxAge},
+	}
+}
+
+func (cc *corsConfig) validate() error {
+	origins := splitList(cc.AllowedOrigins)
+	for _, origin := range origins {
+		if origin == "*" {
+			if cc.AllowCredentials {
+				return errors.New("config: cors-allowed-origins: * can't be combined with cors-allow-credentials")
+			}
+			continue
+		}
+		if err := checkOrigin(origin); err != nil {
+			return fmt.Errorf("config: cors-allowed-origins: %v", err)
+		}
+	}
+	if len(origins) > 0 && len(splitList(cc.AllowedMethods)) == 0 {
+		return errors.New("config: cors-allowed-methods is blank")
+	}
+	for _, method := range splitList(cc.AllowedMethods) {
+		if method != strings.ToUpper(method) || strings.ContainsAny(method, " \t/") {
+			return fmt.Errorf("config: cors-allowed-methods: %q is not a method", method)
+		}
+	}
+	if cc.MaxAge < 0 {
+		return fmt.Errorf("config: cors-max-age: %v is negative", cc.MaxAge)
+	}
+	return nil
+}
+
+// checkOrigin returns an error if origin isn't of the form scheme://host[:port],
+// with at most a leading "*." wildcard in the host.
+func checkOrigin(origin string) error {
+	u, err := url.Parse(strings.Replace(origin, "://*.", "://wildcard.", 1))
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "" || u.Host == "" || u.Path != "" || u.RawQuery != "" || u.User != nil || strings.Contains(u.Host, "*") {
+		return fmt.Errorf("%q is not of the form scheme://host[:port]", origin)
+	}
+	return nil
+}
+
+// handler wraps h with the CORS policy, answering preflight requests itself.
+func (cc *corsConfig) handler(h http.Handler) http.Handler {
+	opts := cors.Options{
+		AllowedOrigins:   splitList(cc.AllowedOrigins),
+		AllowedMethods:   splitList(cc.AllowedMethods),
+		AllowedHeaders:   splitList(cc.AllowedHeaders),
+		AllowCredentials: cc.AllowCredentials,
+		// Let the pages back off when rate limited.
+		ExposedHeaders: []string{"Retry-After"},
+		MaxAge:         int(cc.MaxAge / time.Second),
+	}
+	if len(opts.AllowedOrigins) == 0 {
+		// Otherwise every origin would be allowed.
+		opts.AllowOriginFunc = func(string) bool { return false }
+	}
+	if opts.MaxAge == 0 {
+		opts.MaxAge = -1
+	}
+	return cors.New(opts).Handler(h)
+}
+
+// splitList splits a comma separated list, dropping the blank items.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,127 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/orijtech/media-search/certs"
+	"github.com/orijtech/media-search/config"
+	"github.com/orijtech/media-search/graceful"
+	"github.com/orijtech/media-search/secrets"
+	"github.com/orijtech/media-search/telemetry"
+)
+
+// detailerConfig is the configuration of the detailer.
+type detailerConfig struct {
+	Port           int
+	MongoServerURI string
+
+	// If HedgeDelay is positive, YouTube API calls taking longer are hedged.
+	HedgeDelay     time.Duration
+	MaxHedgedCalls int
+
+	YouTubeAPIKeys secrets.Config
+	// YouTubeAPIKeyDailyQuota, if positive, is the number
+	// of quota units each key may use per day.
+	YouTubeAPIKeyDailyQuota int
+
+	TLS       certs.Config
+	Telemetry telemetry.Config
+	Shutdown  *graceful.Shutdown
+}
+
+func defaultConfig() *detailerConfig {
+	return &detailerConfig{
+		Port:           9944,
+		MongoServerURI: "localhost:27017",
+		MaxHedgedCalls: 2,
+		YouTubeAPIKeys: secrets.Config{
+			Name:           "youtube-api-key",
+			Env:            "YOUTUBE_API_KEY",
+			ReloadInterval: time.Minute,
+		},
+		TLS: certs.Config{
+			DevDir:         "dev-certs",
+			ReloadInterval: time.Minute,
+		},
+		Telemetry: telemetry.Config{
+			ServiceName:         "media-search-detailer",
+			PrometheusAddr:      ":9989",
+			PrometheusNamespace: "mediasearch",
+			ReportingPeriod:     15 * time.Second,
+			SampleRate:          1,
+		},
+		Shutdown: graceful.New(),
+	}
+}
+
+func (dc *detailerConfig) fields() []*config.Field {
+	return []*config.Field{
+		{Name: "port", Env: "MEDIA_SEARCH_DETAILER_PORT", Usage: "the port to run the server on", Value: &dc.Port},
+		{Name: "mongo-server-uri", Env: "MEDIA_SEARCH_MONGO_SERVER_URI", Usage: "the host:port of the MongoDB server", Value: &dc.MongoServerURI},
+		{Name: "hedge-delay", Env: "MEDIA_SEARCH_DETAILER_HEDGE_DELAY", Usage: "if positive, how long to wait on a YouTube API call before hedging it with another", Value: &dc.HedgeDelay},
+		{Name: "max-hedged-calls", Env: "MEDIA_SEARCH_DETAILER_MAX_HEDGED_CALLS", Usage: "the maximum number of concurrent calls per hedged YouTube API call", Value: &dc.MaxHedgedCalls},
+		{Name: "youtube-api-key-daily-quota", Env: "MEDIA_SEARCH_YOUTUBE_API_KEY_DAILY_QUOTA", Usage: "if positive, the quota units each YouTube API key may use per day before it is skipped until the quota resets", Value: &dc.YouTubeAPIKeyDailyQuota},
+	}
+}
+
+func (dc *detailerConfig) validate() error {
+	if err := config.CheckPort("port", dc.Port); err != nil {
+		return err
+	}
+	if dc.MongoServerURI == "" {
+		return errors.New("config: mongo-server-uri is blank")
+	}
+	if dc.HedgeDelay < 0 || dc.MaxHedgedCalls < 1 {
+		return fmt.Errorf("config: hedge-delay %v must not be negative and max-hedged-calls %d must be positive", dc.HedgeDelay, dc.MaxHedgedCalls)
+	}
+	return nil
+}
+
+// loadConfig loads the configuration from the command line, the
+// environment and the -config file, and exits if it is invalid or
+// if it was only asked to be printed.
+func loadConfig() *detailerConfig {
+	dc := defaultConfig()
+	loader := config.NewLoader(flag.CommandLine)
+	loader.Add(dc.fields()...)
+	loader.Add(dc.YouTubeAPIKeys.Fields()...)
+	loader.Add(dc.TLS.Fields()...)
+	loader.Add(dc.Telemetry.Fields()...)
+	loader.Add(dc.Shutdown.Fields()...)
+	loader.Validate(dc.validate)
+	loader.Validate(dc.YouTubeAPIKeys.Validate)
+	loader.Validate(dc.TLS.Validate)
+	loader.Validate(dc.Telemetry.Validate)
+	loader.Validate(dc.Shutdown.Validate)
+	flag.Parse()
+
+	if err := loader.Load(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if loader.PrintRequested() {
+		if err := loader.Print(os.Stdout); err != nil {
+			log.Fatalf("Failed to print the configuration: %v", err)
+		}
+		os.Exit(0)
+	}
+	return dc
+}
@@ -0,0 +1,117 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/mongo"
+)
+
+// pendingDetailsCollection holds the detailing that was still
+// in flight at shutdown, for the next process to resume.
+var pendingDetailsCollection *mongo.Collection
+
+type pendingDetails struct {
+	IDs      []string  `bson:"ids"`
+	QueuedAt time.Time `bson:"queued_at"`
+}
+
+// detailingQueue runs every detailing in the background,
+// keeping track of it so that shutdown can wait for it.
+type detailingQueue struct {
+	wg sync.WaitGroup
+
+	mu       sync.Mutex
+	lastID   int
+	inFlight map[int]*pendingDetails
+}
+
+var detailing = &detailingQueue{inFlight: make(map[int]*pendingDetails)}
+
+func (dq *detailingQueue) start(idList []string) {
+	dq.mu.Lock()
+	dq.lastID++
+	id := dq.lastID
+	dq.inFlight[id] = &pendingDetails{IDs: idList, QueuedAt: time.Now()}
+	dq.mu.Unlock()
+
+	dq.wg.Add(1)
+	go func() {
+		defer dq.wg.Done()
+		performDetailing(idList)
+
+		dq.mu.Lock()
+		delete(dq.inFlight, id)
+		dq.mu.Unlock()
+	}()
+}
+
+// drain waits for the detailing in flight to finish. Whatever
+// hasn't finished by the time ctx is done is persisted instead.
+func (dq *detailingQueue) drain(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		dq.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+	// The deadline is up but persisting is quick and better than losing the
+	// work, hence it gets a short grace period of its own.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for id, pending := range dq.inFlight {
+		if _, err := pendingDetailsCollection.InsertOne(ctx, pending); err != nil {
+			return err
+		}
+		delete(dq.inFlight, id)
+	}
+	return nil
+}
+
+// resumePendingDetailing restarts the detailing persisted at the last shutdown.
+// Each is claimed by deleting it, so that replicas starting at the same time
+// never resume the same one, nor delete one that another replica persisted
+// meanwhile. If this process is in turn stopped early, they are persisted afresh.
+func resumePendingDetailing(ctx context.Context) error {
+	resumed := 0
+	for {
+		pending := new(pendingDetails)
+		switch err := pendingDetailsCollection.FindOneAndDelete(ctx, bson.NewDocument()).Decode(pending); err {
+		case nil:
+			detailing.start(pending.IDs)
+			resumed++
+
+		case bson.ErrElementNotFound, mongo.ErrNoDocuments:
+			if resumed > 0 {
+				logger.InfoContext(ctx, "Resumed the pending detailing", "count", resumed)
+			}
+			return nil
+
+		default:
+			return err
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package discovery finds the replicas of a gRPC service, from a list
+// of addresses, DNS SRV records or a watched file, and balances the
+// calls to the service over those of them that are healthy.
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/balancer/leastrequest"
+	"google.golang.org/grpc/balancer/roundrobin"
+
+	// Registers the client side health checking enabled by the service config.
+	_ "google.golang.org/grpc/health"
+
+	"github.com/orijtech/media-search/config"
+)
+
+// Scheme is the scheme of the targets that the replicas are dialed at.
+const Scheme = "media-search"
+
+// The balancers, how each call picks the replica it is made to.
+const (
+	// RoundRobin makes the calls to each replica in turn.
+	RoundRobin = "round_robin"
+	// LeastLoaded makes each call to the replica with the fewest calls
+	// in flight, out of two picked at random.
+	LeastLoaded = "least_loaded"
+)
+
+var (
+	KeyService = mustKey("service")
+
+	resolvedAddrs = stats.Int64("resolved_addresses", "The number of addresses a service resolved to", "1")
+)
+
+// Views are the views for the metrics recorded by this package.
+var Views = []*view.View{
+	{
+		Name: "resolved_addresses", Description: "addresses a service resolved to",
+		Measure: resolvedAddrs, Aggregation: view.LastValue(), TagKeys: []tag.Key{KeyService},
+	},
+}
+
+// Config selects the source of the addresses of the replicas of the service
+// Name: a file if File is set, otherwise the DNS SRV records of SRV if set,
+// otherwise the comma separated Addrs.
+type Config struct {
+	Name string
+
+	Addrs           string
+	SRV             string
+	File            string
+	Balancer        string
+	ResolveInterval time.Duration
+}
+
+// Fields returns the settings of c, named after c.Name, to be loaded by a config.Loader.
+func (c *Config) Fields() []*config.Field {
+	env := "MEDIA_SEARCH_" + strings.ToUpper(strings.Replace(c.Name, "-", "_", -1))
+	return []*config.Field{
+		{Name: c.Name + "-addr", Env: env + "_ADDR", Usage: fmt.Sprintf("the comma separated addresses of the %s replicas", c.Name), Value: &c.Addrs},
+		{Name: c.Name + "-srv", Env: env + "_SRV", Usage: fmt.Sprintf("if set, the DNS SRV name that the %s replicas are looked up at", c.Name), Value: &c.SRV},
+		{Name: c.Name + "-addrs-file", Env: env + "_ADDRS_FILE", Usage: fmt.Sprintf("if set, the file that the addresses of the %s replicas are read from, one per line", c.Name), Value: &c.File},
+		{Name: c.Name + "-balancer", Env: env + "_BALANCER", Usage: fmt.Sprintf("how the calls are spread over the %s replicas: %s or %s", c.Name, RoundRobin, LeastLoaded), Value: &c.Balancer},
+		{Name: c.Name + "-resolve-interval", Env: env + "_RESOLVE_INTERVAL", Usage: fmt.Sprintf("how often the addresses of the %s replicas are looked up", c.Name), Value: &c.ResolveInterval},
+	}
+}
+
+// Validate returns an error if no source of addresses or more than one
+// is set, if an address is malformed or if the balancer is unknown.
+func (c *Config) Validate() error {
+	switch {
+	case c.File != "" && c.SRV != "":
+		return fmt.Errorf("config: %s-addrs-file and %s-srv are mutually exclusive", c.Name, c.Name)
+	case c.File == "" && c.SRV == "":
+		addrs := splitList(c.Addrs)
+		if len(addrs) == 0 {
+			return fmt.Errorf("config: %s-addr is blank", c.Name)
+		}
+		for _, addr := range addrs {
+			if err := config.CheckAddr(c.Name+"-addr", addr); err != nil {
+				return err
+			}
+		}
+	}
+	switch c.Balancer {
+	case RoundRobin, LeastLoaded:
+	default:
+		return fmt.Errorf("config: %s-balancer: %q is neither %s nor %s", c.Name, c.Balancer, RoundRobin, LeastLoaded)
+	}
+	return config.CheckPositive(c.Name+"-resolve-interval", c.ResolveInterval)
+}
+
+// Source returns the source of the addresses configured.
+func (c *Config) Source() Source {
+	switch {
+	case c.File != "":
+		return File(c.File)
+	case c.SRV != "":
+		return SRV(c.SRV)
+	default:
+		return Static(splitList(c.Addrs)...)
+	}
+}
+
+// Dial dials the replicas of the service, with opts, balancing the calls over
+// them. Those that report themselves unhealthy over the standard gRPC health
+// service, e.g. while shutting down, are left out until they recover.
+func (c *Config) Dial(opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	if c.Name == "" {
+		return nil, errors.New("discovery: the service has no name")
+	}
+	b := &builder{service: c.Name, source: c.Source(), interval: c.ResolveInterval}
+	opts = append([]grpc.DialOption{
+		grpc.WithResolvers(b),
+		grpc.WithDefaultServiceConfig(serviceConfig(c.Balancer)),
+	}, opts...)
+	return grpc.Dial(Scheme+":///"+c.Name, opts...)
+}
+
+func serviceConfig(balancer string) string {
+	lb := `{"` + roundrobin.Name + `": {}}`
+	if balancer == LeastLoaded {
+		lb = `{"` + leastrequest.Name + `": {"choiceCount": 2}}`
+	}
+	return `{"loadBalancingConfig": [` + lb + `], "healthCheckConfig": {"serviceName": ""}}`
+}
+
+// splitList splits a comma separated list, dropping the blank items.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func mustKey(sk string) tag.Key {
+	k, err := tag.NewKey(sk)
+	if err != nil {
+		log.Fatalf("Creating new key %q error: %v", sk, err)
+	}
+	return k
+}
@@ -0,0 +1,165 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"sort"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+	"google.golang.org/grpc/resolver"
+)
+
+// minResolveInterval bounds how often gRPC may have the addresses
+// looked up again, as it asks to every time a connection fails.
+const minResolveInterval = 5 * time.Second
+
+// builder builds the resolvers of a service, looking up its
+// addresses from source every interval.
+type builder struct {
+	service  string
+	source   Source
+	interval time.Duration
+}
+
+var _ resolver.Builder = (*builder)(nil)
+
+func (b *builder) Scheme() string { return Scheme }
+
+func (b *builder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &sourceResolver{
+		builder:    b,
+		cc:         cc,
+		cancel:     cancel,
+		resolveNow: make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+	go r.watch(ctx)
+	return r, nil
+}
+
+type sourceResolver struct {
+	*builder
+	cc         resolver.ClientConn
+	cancel     func()
+	resolveNow chan struct{}
+	done       chan struct{}
+
+	// addrs are the addresses last passed to cc.
+	addrs []string
+}
+
+func (r *sourceResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.resolveNow <- struct{}{}:
+	default:
+	}
+}
+
+func (r *sourceResolver) Close() {
+	r.cancel()
+	<-r.done
+}
+
+var errNoAddrs = errors.New("discovery: no addresses")
+
+func (r *sourceResolver) watch(ctx context.Context) {
+	defer close(r.done)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		last := time.Now()
+		r.resolve(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.resolveNow:
+			if wait := minResolveInterval - time.Since(last); wait > 0 {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(wait):
+				}
+			}
+		}
+	}
+}
+
+// resolve looks the addresses up and passes them to gRPC if they changed. A
+// failed lookup is reported to gRPC, which keeps using the current addresses.
+func (r *sourceResolver) resolve(ctx context.Context) {
+	lookupCtx, cancel := context.WithTimeout(ctx, r.interval)
+	addrs, err := r.source.Lookup(lookupCtx)
+	cancel()
+	if err == nil && len(addrs) == 0 {
+		err = errNoAddrs
+	}
+	if err != nil {
+		// Unless the resolver was closed in the meantime.
+		if ctx.Err() == nil {
+			slog.Warn("Looking up the addresses error", "service", r.service, "source", r.source.String(), "err", err)
+			r.cc.ReportError(err)
+		}
+		return
+	}
+
+	sort.Strings(addrs)
+	if equal(addrs, r.addrs) {
+		return
+	}
+	state := resolver.State{Addresses: make([]resolver.Address, len(addrs))}
+	for i, addr := range addrs {
+		state.Addresses[i] = resolver.Address{Addr: addr, ServerName: serverName(addr)}
+	}
+	if err := r.cc.UpdateState(state); err != nil {
+		slog.Warn("Updating the addresses error", "service", r.service, "err", err)
+	}
+	slog.Info("Resolved the addresses", "service", r.service, "source", r.source.String(), "addrs", addrs)
+	r.addrs = addrs
+
+	tctx, _ := tag.New(context.Background(), tag.Upsert(KeyService, r.service))
+	stats.Record(tctx, resolvedAddrs.M(int64(len(addrs))))
+}
+
+// serverName returns the name that the certificate of the server at addr
+// is verified against, its host or localhost if blank.
+func serverName(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return "localhost"
+	}
+	return host
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
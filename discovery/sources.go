@@ -0,0 +1,109 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Source looks up the addresses, of the form host:port, of the replicas of a service.
+type Source interface {
+	Lookup(ctx context.Context) ([]string, error)
+	String() string
+}
+
+// Static returns a Source of a fixed list of addresses.
+func Static(addrs ...string) Source {
+	return staticSource(addrs)
+}
+
+type staticSource []string
+
+func (ss staticSource) Lookup(context.Context) ([]string, error) {
+	return append([]string(nil), ss...), nil
+}
+
+func (ss staticSource) String() string {
+	return strings.Join(ss, ",")
+}
+
+// SRV returns a Source of the targets of the DNS SRV records of name,
+// e.g. _grpc._tcp.backends.example.com. Only the targets of the highest
+// priority, the lowest value, are returned; the balancer ignores weights.
+func SRV(name string) Source {
+	return srvSource(name)
+}
+
+type srvSource string
+
+func (ss srvSource) Lookup(ctx context.Context) ([]string, error) {
+	_, records, err := net.DefaultResolver.LookupSRV(ctx, "", "", string(ss))
+	if err != nil {
+		return nil, err
+	}
+	var addrs []string
+	for _, srv := range records {
+		// The records are sorted by priority.
+		if srv.Priority != records[0].Priority {
+			break
+		}
+		host := strings.TrimSuffix(srv.Target, ".")
+		addrs = append(addrs, net.JoinHostPort(host, strconv.Itoa(int(srv.Port))))
+	}
+	return addrs, nil
+}
+
+func (ss srvSource) String() string {
+	return "SRV " + string(ss)
+}
+
+// File returns a Source of the addresses in the file at path, one per
+// line. Blank lines and those starting with "#" are skipped.
+func File(path string) Source {
+	return fileSource(path)
+}
+
+type fileSource string
+
+func (fs fileSource) Lookup(context.Context) ([]string, error) {
+	blob, err := ioutil.ReadFile(string(fs))
+	if err != nil {
+		return nil, err
+	}
+	var addrs []string
+	scanner := bufio.NewScanner(bytes.NewReader(blob))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(line); err != nil {
+			return nil, fmt.Errorf("%s: %v", fs, err)
+		}
+		addrs = append(addrs, line)
+	}
+	return addrs, scanner.Err()
+}
+
+func (fs fileSource) String() string {
+	return "file " + string(fs)
+}
@@ -16,9 +16,13 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
 	"reflect"
 	"time"
 
@@ -27,73 +31,54 @@ import (
 	"github.com/mongodb/mongo-go-driver/bson"
 	"github.com/mongodb/mongo-go-driver/mongo"
 
-	"contrib.go.opencensus.io/exporter/stackdriver"
-	xray "github.com/census-instrumentation/opencensus-go-exporter-aws"
-	"go.opencensus.io/exporter/prometheus"
 	"go.opencensus.io/plugin/ocgrpc"
 	"go.opencensus.io/plugin/ochttp"
 	"go.opencensus.io/stats"
 	"go.opencensus.io/stats/view"
 	"go.opencensus.io/tag"
 	"go.opencensus.io/trace"
-	"go.opencensus.io/zpages"
 
+	v1 "github.com/orijtech/media-search/api/v1"
+	"github.com/orijtech/media-search/auth"
+	"github.com/orijtech/media-search/discovery"
+	"github.com/orijtech/media-search/health"
+	"github.com/orijtech/media-search/ratelimit"
 	"github.com/orijtech/media-search/rpc"
-	"github.com/orijtech/otils"
+	"github.com/orijtech/media-search/telemetry"
 )
 
+var mongoClient *mongo.Client
+var mediaSearchesDB *mongo.Database
 var ytSearchesCollection *mongo.Collection
-var genIDClient rpc.GenIDClient
-var searchClient rpc.SearchClient
-
-func init() {
-	se, err := stackdriver.NewExporter(stackdriver.Options{
-		MetricPrefix: "mediasearch",
-		ProjectID:    otils.EnvOrAlternates("OPENCENSUS_GCP_PROJECTID", "census-demos"),
-	})
-	if err != nil {
-		log.Fatalf("Stackdriver newExporter error: %v", err)
-	}
-	xe, err := xray.NewExporter(xray.WithVersion("latest"))
-	if err != nil {
-		log.Fatalf("AWS X-Ray newExporter error: %v", err)
-	}
-	pe, err := prometheus.NewExporter(prometheus.Options{Namespace: "mediasearch"})
-	if err != nil {
-		log.Fatalf("Prometheus newExporter error: %v", err)
-	}
 
-	// Now register the exporters
-	trace.RegisterExporter(se)
-	trace.RegisterExporter(xe)
-	view.RegisterExporter(se)
-	view.RegisterExporter(pe)
-
-	// Always sample for demo purposes
-	trace.ApplyConfig(trace.Config{DefaultSampler: trace.AlwaysSample()})
-
-	// Serve the Prometheus metrics
-	go func() {
-		mux := http.NewServeMux()
-		mux.Handle("/metrics", pe)
-		log.Fatal(http.ListenAndServe(":9888", mux))
-	}()
-
-	go func() {
-		mux := http.NewServeMux()
-		zpages.Handle(mux, "/debug")
-		log.Fatal(http.ListenAndServe(":7788", mux))
-	}()
+// logger is set up in main, from the telemetry configuration.
+var logger = slog.Default()
+var idGenerator rpc.IDGenerator
+var searchClient rpc.SearchClient
 
-	view.SetReportingPeriod(10 * time.Second)
+// missLimiter throttles the searches that miss the cache, set up in main.
+var missLimiter *ratelimit.Limiter
 
+func init() {
 	// Register the views from MongoDB's Go driver
 	if err := view.Register(mongo.AllViews...); err != nil {
 		log.Fatalf("Failed to register MongoDB views: %v", err)
 	}
+	if err := view.Register(telemetry.Views...); err != nil {
+		log.Fatalf("Failed to register the latency views: %v", err)
+	}
+	if err := view.Register(auth.Views...); err != nil {
+		log.Fatalf("Failed to register the authentication views: %v", err)
+	}
+	if err := view.Register(ratelimit.Views...); err != nil {
+		log.Fatalf("Failed to register the rate limiting views: %v", err)
+	}
+	if err := view.Register(discovery.Views...); err != nil {
+		log.Fatalf("Failed to register the service discovery views: %v", err)
+	}
 
 	// And then for the custom views
-	err = view.Register([]*view.View{
+	err := view.Register([]*view.View{
 		{Name: "cache_hits", Description: "cache hits", Measure: cacheHits, Aggregation: view.Count()},
 		{Name: "cache_misses", Description: "cache misses", Measure: cacheMisses, Aggregation: view.Count()},
 		{
@@ -106,39 +91,73 @@ func init() {
 		}, {
 			Name: "mongo_errors", Description: "MongoDB errors",
 			Measure: mongoErrors, Aggregation: view.Count(),
+		}, {
+			Name: "trending_refreshes", Description: "trending feed refreshes",
+			Measure: trendingRefreshes, Aggregation: view.Count(),
+		}, {
+			Name: "suggest_refreshes", Description: "suggestion trie rebuilds",
+			Measure: suggestRefreshes, Aggregation: view.Count(),
+		}, {
+			Name: "search_events_dropped", Description: "search events dropped",
+			Measure: droppedSearchEvents, Aggregation: view.Count(),
 		},
 	}...)
 	if err != nil {
 		log.Fatalf("Failed to register custom views: %v", err)
 	}
 
-	log.Printf("Successfully finished exporter and view registration")
+	log.Printf("Successfully finished view registration")
+}
 
-	// Log into MongoDB
-	mongoServerURI := otils.EnvOrAlternates("MEDIA_SEARCH_MONGO_SERVER_URI", "localhost:27017")
-	mongoClient, err := mongo.NewClient("mongodb://" + mongoServerURI)
-	log.Printf("mongoServerURI: %q\n", mongoServerURI)
+func main() {
+	cfg := loadConfig()
+	trendingRefreshInterval = cfg.TrendingRefreshInterval
+	suggestRefreshInterval = cfg.SuggestRefreshInterval
+	suggestLookback = cfg.SuggestLookback
+	missLimiter = cfg.RateLimit.Misses()
+
+	var err error
+	logger, err = telemetry.NewLogger(os.Stderr, &cfg.Telemetry)
 	if err != nil {
-		log.Fatalf("Failed to log into Mongo error: %v", err)
+		log.Fatalf("Failed to create the logger: %v", err)
 	}
-	// Connect to the server
-	if err := mongoClient.Connect(context.Background()); err != nil {
-		log.Fatalf("Failed to connect to the MongoDB server: %v", err)
+	slog.SetDefault(logger)
+	crt, err := cfg.TLS.Load(cfg.Telemetry.ServiceName)
+	if err != nil {
+		log.Fatalf("Failed to load the TLS certificates: %v", err)
+	}
+	go crt.Watch(context.Background(), cfg.TLS.ReloadInterval)
+	cfg.Telemetry.TLS = crt.ServerTLS(tls.NoClientCert)
+	tel, err := telemetry.Setup(&cfg.Telemetry)
+	if err != nil {
+		log.Fatalf("Failed to set up telemetry: %v", err)
 	}
-	// Create or get the searches collection.
-	ytSearchesCollection = mongoClient.Database("media-searches").Collection("youtube_searches")
-}
 
-func main() {
-	// Firstly dial to the search service
-	searchAddr := ":8899"
-	conn, err := grpc.Dial(searchAddr, grpc.WithInsecure(), grpc.WithStatsHandler(&ocgrpc.ClientHandler{}))
+	authn, err := cfg.Auth.Load(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to load the authentication keys: %v", err)
+	}
+	if len(authn) == 0 {
+		logger.Warn("No authentication configured, the API is open to everyone")
+	}
+
+	connectToMongo(cfg.MongoServerURI)
+	indexSearchLog(context.Background(), cfg.SearchLogRetention)
+
+	// Firstly dial to the replicas of the search service
+	conn, err := cfg.Search.Dial(crt.DialOption(),
+		grpc.WithStatsHandler(&ocgrpc.ClientHandler{}),
+		// Let the backends know who every call is made on behalf of.
+		grpc.WithUnaryInterceptor(auth.UnaryClientInterceptor()))
 	if err != nil {
 		log.Fatalf("Failed to dial to gRPC server: %v", err)
 	}
 	searchClient = rpc.NewSearchClient(conn)
-	genIDClient = rpc.NewGenIDClient(conn)
-	log.Printf("Successfully dialed to the gRPC {id, search} services at %q", searchAddr)
+	idGenerator = rpc.LocalIDGenerator()
+	if cfg.GenID == genIDRemote {
+		idGenerator = rpc.RemoteIDGenerator(rpc.NewGenIDClient(conn), "")
+	}
+	logger.Info("Successfully dialed to the gRPC {id, search} services", "source", cfg.Search.Source().String(), "balancer", cfg.Search.Balancer)
 
 	// Subscribe to every view available since the service is a mix of gRPC and HTTP, client and server services.
 	allViews := append(ochttp.DefaultClientViews, ochttp.DefaultServerViews...)
@@ -148,19 +167,91 @@ func main() {
 		log.Fatalf("Failed to register all the default {ocgrpc, ochttp} views: %v", err)
 	}
 
-	addr := ":9778"
+	// Keep the suggestions fresh from the queries seen so far.
+	go refreshSuggestionsPeriodically()
+	// Persist search events in the background.
+	go writeSearchEvents()
+
+	addr := fmt.Sprintf(":%d", cfg.Port)
 	mux := http.NewServeMux()
-	mux.HandleFunc("/search", search)
+	// The API requires authentication and is rate limited by the IP address
+	// before it, so that failing it costs little, and by the client
+	// authenticated after it. The static files and health checks are neither.
+	ips, hits := cfg.RateLimit.IPs(), cfg.RateLimit.Hits()
+	api := func(h http.HandlerFunc) http.Handler { return ips.LimitByIP(authn.Require(hits.Limit(h))) }
+	mux.Handle("/search", api(search))
+	mux.Handle("/v1/search", api(searchV1))
+	mux.Handle("/trending", api(trending))
+	mux.Handle("/suggest", api(suggest))
 	mux.Handle("/", http.FileServer(http.Dir("./static")))
 
+	// The analytics tell what everyone searches for, hence they are
+	// only served to the operators, on the zPages listener.
+	analytics := map[string]http.HandlerFunc{
+		"/analytics/top-queries":  topQueries,
+		"/analytics/zero-results": zeroResultQueries,
+		"/analytics/hit-ratio":    cacheHitRatio,
+	}
+	served := true
+	for pattern, h := range analytics {
+		served = tel.Handle(pattern, h)
+	}
+	if !served {
+		logger.Warn("No zPages listener, hence the analytics aren't served")
+	}
+
+	checker := health.NewChecker()
+	checker.AddReadinessCheck("mongodb", health.MongoCheck(mediaSearchesDB))
+	// The search backend reports itself unready if it can't reach YouTube,
+	// yet the cached searches and feeds, the suggestions and the static
+	// files are still served without it, hence it only degrades the frontend.
+	checker.AddDegradedCheck("search", health.GRPCCheck(conn, "rpc.Search"))
+	checker.Register(mux)
+
 	h := &ochttp.Handler{
-		// Wrap the handler with CORS
-		Handler: otils.CORSMiddlewareAllInclusive(mux),
+		Propagation:      telemetry.HTTPFormat,
+		IsHealthEndpoint: health.IsEndpoint,
+		Handler:          cfg.CORS.handler(mux),
 	}
-	log.Printf("Serving on %q", addr)
-	if err := http.ListenAndServe(addr, h); err != nil {
-		log.Fatalf("ListenAndServe err: %v", err)
+	srv := &http.Server{Addr: addr, Handler: h}
+	go func() {
+		logger.Info("Serving", "addr", addr, "tls", crt != nil)
+		// Browsers can't present client certificates, hence none is asked for.
+		if err := crt.ListenAndServe(srv, tls.NoClientCert); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("ListenAndServe err: %v", err)
+		}
+	}()
+
+	// On shutdown, the searches in flight finish first, then their events
+	// are written and only then are their dependencies released.
+	shutdown := cfg.Shutdown
+	shutdown.Drain = checker.Drain
+	shutdown.Add("http server", srv.Shutdown)
+	shutdown.Add("search events", flushSearchEvents)
+	shutdown.Add("grpc connection", func(context.Context) error { return conn.Close() })
+	shutdown.Add("mongodb", mongoClient.Disconnect)
+	shutdown.Add("telemetry", func(context.Context) error { return tel.Close() })
+	shutdown.Wait()
+}
+
+func connectToMongo(mongoServerURI string) {
+	var err error
+	mongoClient, err = mongo.NewClient("mongodb://" + mongoServerURI)
+	logger.Info("Connecting to MongoDB", "uri", mongoServerURI)
+	if err != nil {
+		log.Fatalf("Failed to log into Mongo error: %v", err)
 	}
+	// Connect to the server
+	if err := mongoClient.Connect(context.Background()); err != nil {
+		log.Fatalf("Failed to connect to the MongoDB server: %v", err)
+	}
+	mediaSearchesDB = mongoClient.Database("media-searches")
+	// Create or get the searches collection.
+	ytSearchesCollection = mediaSearchesDB.Collection("youtube_searches")
+	// Create or get the trending feeds collection.
+	ytTrendingCollection = mediaSearchesDB.Collection("youtube_trending")
+	// Create or get the search log collection.
+	searchLogCollection = mediaSearchesDB.Collection("search_log")
 }
 
 type dbCacheKV struct {
@@ -170,29 +261,44 @@ type dbCacheKV struct {
 	CacheTime time.Time `json:"ct" bson:"ct,omitempty"`
 }
 
-var rpcNothing = new(rpc.Nothing)
-
 func search(w http.ResponseWriter, r *http.Request) {
-	sc := trace.FromContext(r.Context()).SpanContext()
-	log.Printf("search here: %+v\n", sc)
-	ctx, span := trace.StartSpan(r.Context(), "/search")
-	defer span.End()
+	serveSearch(w, r, writeResults)
+}
 
-	if r.Method == "OPTIONS" {
-		return
-	}
+// searchV1 serves /v1/search, whose JSON is the stable schema of package v1.
+func searchV1(w http.ResponseWriter, r *http.Request) {
+	serveSearch(w, r, writeV1Results)
+}
+
+// resultsWriter writes the results found for q, blob being their JSON encoding as cached.
+type resultsWriter func(w http.ResponseWriter, r *http.Request, format string, q *rpc.Query, blob []byte)
+
+// serveSearch serves a search from the cache, or otherwise from the search
+// backends, caching the results found, and writes them with write.
+func serveSearch(w http.ResponseWriter, r *http.Request, write resultsWriter) {
+	ctx, span := trace.StartSpan(r.Context(), r.URL.Path)
+	defer span.End()
 
 	q, err := rpc.ExtractQuery(ctx, r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		rpc.WriteHTTPError(w, err)
 		return
 	}
 
 	keywords := q.Keywords
 	if keywords == "" {
-		http.Error(w, "Expecting keywords", http.StatusBadRequest)
+		rpc.WriteHTTPError(w, rpc.Errorf(rpc.CodeInvalidQuery, "Expecting keywords"))
+		return
+	}
+	format, err := rpc.NegotiateFormat(r)
+	if err != nil {
+		rpc.WriteHTTPError(w, err)
 		return
 	}
+	// Record how this search went, for suggestions and analytics.
+	ev := newSearchEvent(ctx, r, keywords)
+	defer recordSearchEvent(ev)
+
 	filter := bson.NewDocument(bson.EC.String("key", q.Keywords))
 
 	span.Annotate([]trace.Attribute{
@@ -200,6 +306,7 @@ func search(w http.ResponseWriter, r *http.Request) {
 		trace.StringAttribute("driver", "go"),
 	}, "Checking cache if the query is present")
 
+	lookupStart := time.Now()
 	dbRes := ytSearchesCollection.FindOne(ctx, filter)
 	// 1. Firstly check if this has been cached before
 	cachedKV := new(dbCacheKV)
@@ -207,22 +314,28 @@ func search(w http.ResponseWriter, r *http.Request) {
 	switch err := dbRes.Decode(cachedKV); err {
 	case nil: // Cache hit!
 		if !reflect.DeepEqual(cachedKV, blankDBKV) {
+			telemetry.RecordLatency(ctx, lookupStart, "cache_lookup", telemetry.ProviderMongoDB, telemetry.ResultHit)
 			span.Annotate([]trace.Attribute{
 				trace.BoolAttribute("hit", true),
 				trace.StringAttribute("db", "mongodb"),
 				trace.StringAttribute("driver", "go"),
 			}, "Cache hit")
 			stats.Record(ctx, cacheHits.M(1))
-			w.Write(cachedKV.Value)
+			ev.Hit = true
+			ev.ResultCount = countResults(cachedKV.Value)
+			write(w, r, format, q, cachedKV.Value)
 			return
 		}
 
 		// Otherwise this is false cache hit!
+		telemetry.RecordLatency(ctx, lookupStart, "cache_lookup", telemetry.ProviderMongoDB, telemetry.ResultMiss)
 
 	case bson.ErrElementNotFound, mongo.ErrNoDocuments:
 		// Cache miss, now retrieve the results below
+		telemetry.RecordLatency(ctx, lookupStart, "cache_lookup", telemetry.ProviderMongoDB, telemetry.ResultMiss)
 
 	default:
+		telemetry.RecordLatency(ctx, lookupStart, "cache_lookup", telemetry.ProviderMongoDB, telemetry.ResultError)
 		stats.Record(ctx, mongoErrors.M(1))
 		// We've failed to decode but oh well, that was just a cache miss
 		// the user should still get their result back! Thus continue below
@@ -231,11 +344,24 @@ func search(w http.ResponseWriter, r *http.Request) {
 
 	// 2. Otherwise that was a cache-miss, now retrieve it then save it
 	stats.Record(ctx, cacheMisses.M(1))
+	// Misses cost YouTube API quota, hence their own, lower, rate limit.
+	if missLimiter.Throttle(ctx, w, ratelimit.ClientKey(r)) {
+		ev.Err = string(rpc.CodeRateLimited)
+		return
+	}
+	// Cache misses cost YouTube API quota so they're always traced.
+	ctx, missSpan := telemetry.StartSampledSpan(ctx, "cache-miss")
+	defer missSpan.End()
 
 	// 3. Get the global CacheID
-	cacheID, err := genIDClient.NewID(ctx, rpcNothing)
+	genIDStart := time.Now()
+	cacheID, err := idGenerator.NewID(ctx)
+	telemetry.RecordLatency(ctx, genIDStart, "genid", telemetry.ProviderGenID, telemetry.ResultOf(err))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		telemetry.RecordError(ctx, err)
+		logger.ErrorContext(ctx, "Generating the cache ID error", "err", err)
+		ev.Err = string(rpc.FromError(err).Code)
+		rpc.WriteHTTPError(w, err)
 		return
 	}
 
@@ -245,7 +371,9 @@ func search(w http.ResponseWriter, r *http.Request) {
 		trace.StringAttribute("driver", "go"),
 	}, "Cache miss, hence YouTube API search")
 
+	searchStart := time.Now()
 	results, err := searchClient.SearchIt(ctx, q)
+	telemetry.RecordLatency(ctx, searchStart, "search", telemetry.ProviderSearch, telemetry.ResultOf(err))
 	if err != nil {
 		stats.Record(ctx, youtubeAPIErrors.M(1))
 		span.Annotate([]trace.Attribute{
@@ -253,30 +381,76 @@ func search(w http.ResponseWriter, r *http.Request) {
 			trace.StringAttribute("db", "mongodb"),
 			trace.StringAttribute("driver", "go"),
 		}, "YouTube API search error")
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		telemetry.RecordError(ctx, err)
+		logger.ErrorContext(ctx, "Search error", "keywords", keywords, "err", err)
+		ev.Err = string(rpc.FromError(err).Code)
+		rpc.WriteHTTPError(w, err)
 		return
 	}
 
+	for _, result := range results.Results {
+		ev.ResultCount += int64(len(result.GetItems()))
+	}
+
 	outBlob, err := json.Marshal(results.Results)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		ev.Err = string(rpc.FromError(err).Code)
+		rpc.WriteHTTPError(w, err)
 		return
 	}
 
 	// 4. Now cache it so that next time it'll be a hit.
 	insertKV := &dbCacheKV{
-		CacheID:   cacheID.Value,
+		CacheID:   cacheID,
 		Key:       keywords,
 		Value:     outBlob,
 		CacheTime: time.Now(),
 	}
 
-	if _, err := ytSearchesCollection.InsertOne(ctx, insertKV); err != nil {
+	insertStart := time.Now()
+	_, err = ytSearchesCollection.InsertOne(ctx, insertKV)
+	telemetry.RecordLatency(ctx, insertStart, "cache_insert", telemetry.ProviderMongoDB, telemetry.ResultOf(err))
+	if err != nil {
+		logger.WarnContext(ctx, "Caching the search results error", "keywords", keywords, "err", err)
 		ctx, _ = tag.New(ctx, tag.Upsert(keyCacheType, "mongo"))
 		stats.Record(ctx, cacheInsertionErrors.M(1))
 	}
 
-	_, _ = w.Write(outBlob)
+	write(w, r, format, q, outBlob)
+}
+
+// writeResults writes the results found for q, blob being their JSON encoding
+// as cached, in format. Unlike the other formats, which are of rpc.SearchResults,
+// JSON is written as cached, as an array of the results of each page.
+func writeResults(w http.ResponseWriter, r *http.Request, format string, q *rpc.Query, blob []byte) {
+	if format == rpc.FormatJSON {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("Vary", "Accept")
+		_, _ = w.Write(blob)
+		return
+	}
+	var results []*rpc.SearchResult
+	if err := json.Unmarshal(blob, &results); err != nil {
+		rpc.WriteHTTPError(w, rpc.Errorf(rpc.CodeInternal, "Decoding the results: %v", err))
+		return
+	}
+	rpc.WriteSearchResults(w, r, format, q, &rpc.SearchResults{Results: results})
+}
+
+// writeV1Results is writeResults but for JSON, which is written in the schema of package v1.
+func writeV1Results(w http.ResponseWriter, r *http.Request, format string, q *rpc.Query, blob []byte) {
+	if format != rpc.FormatJSON {
+		writeResults(w, r, format, q, blob)
+		return
+	}
+	var results []*rpc.SearchResult
+	if err := json.Unmarshal(blob, &results); err != nil {
+		rpc.WriteHTTPError(w, rpc.Errorf(rpc.CodeInternal, "Decoding the results: %v", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Add("Vary", "Accept")
+	_ = json.NewEncoder(w).Encode(v1.FromResults(q.Keywords, results))
 }
 
 var (
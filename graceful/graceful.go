@@ -0,0 +1,123 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package graceful shuts a service down on SIGINT or SIGTERM in order:
+// it stops taking traffic, lets the work in flight finish and only then
+// releases what that work needs, such as the telemetry exporters and MongoDB.
+package graceful
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/orijtech/media-search/config"
+)
+
+// Shutdown runs the steps added to it once the process is signalled.
+type Shutdown struct {
+	// Drain, if set, is called as soon as the process is signalled,
+	// e.g. to fail the readiness checks. The steps are run DrainDelay
+	// later, so that load balancers have stopped sending traffic by then.
+	Drain      func()
+	DrainDelay time.Duration
+
+	// Timeout bounds how long the steps take altogether.
+	Timeout time.Duration
+
+	steps []*step
+}
+
+type step struct {
+	name string
+	fn   func(context.Context) error
+}
+
+// New returns a Shutdown without a drain delay, which times out after 30s.
+func New() *Shutdown {
+	return &Shutdown{Timeout: 30 * time.Second}
+}
+
+// Fields returns the settings of s, to be loaded by a config.Loader.
+func (s *Shutdown) Fields() []*config.Field {
+	return []*config.Field{
+		{Name: "drain-delay", Env: "MEDIA_SEARCH_DRAIN_DELAY", Usage: "how long to keep serving once signalled to stop, while reported unready", Value: &s.DrainDelay},
+		{Name: "shutdown-timeout", Env: "MEDIA_SEARCH_SHUTDOWN_TIMEOUT", Usage: "how long to wait for the work in flight to finish once signalled to stop", Value: &s.Timeout},
+	}
+}
+
+// Validate returns an error if the delay or the timeout is negative.
+func (s *Shutdown) Validate() error {
+	if s.DrainDelay < 0 || s.Timeout < 0 {
+		return fmt.Errorf("graceful: the drain delay %v and timeout %v must not be negative", s.DrainDelay, s.Timeout)
+	}
+	return nil
+}
+
+// Add appends a step, named for the logs, to those run in order on shutdown.
+// Each step is run even if those before it failed or ran out of time.
+func (s *Shutdown) Add(name string, fn func(context.Context) error) {
+	s.steps = append(s.steps, &step{name: name, fn: fn})
+}
+
+// Wait blocks until the process receives SIGINT or SIGTERM and then shuts it
+// down. A second signal terminates the process without waiting any longer.
+func (s *Shutdown) Wait() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	sig := <-signals
+	signal.Stop(signals)
+
+	slog.Info("Shutting down", "signal", sig.String(), "drain_delay", s.DrainDelay, "timeout", s.Timeout)
+	if s.Drain != nil {
+		s.Drain()
+	}
+	<-time.After(s.DrainDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.Timeout)
+	defer cancel()
+	for _, st := range s.steps {
+		start := time.Now()
+		if err := st.fn(ctx); err != nil {
+			slog.Error("Shutdown step error", "step", st.name, "err", err)
+			continue
+		}
+		slog.Info("Shutdown step done", "step", st.name, "took", time.Since(start))
+	}
+}
+
+// GRPCServer returns a step that stops srv once the RPCs in
+// flight are done, cancelling those left when ctx is done.
+func GRPCServer(srv *grpc.Server) func(context.Context) error {
+	return func(ctx context.Context) error {
+		stopped := make(chan struct{})
+		go func() {
+			srv.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+			return nil
+		case <-ctx.Done():
+			srv.Stop()
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/mongo"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// MongoCheck pings the MongoDB server of db.
+func MongoCheck(db *mongo.Database) Check {
+	ping := bson.NewDocument(bson.EC.Int32("ping", 1))
+	return func(ctx context.Context) error {
+		_, err := db.RunCommand(ctx, ping)
+		return err
+	}
+}
+
+// GRPCCheck asks the health service at the other end of
+// conn whether service, e.g. "rpc.Search", is serving.
+func GRPCCheck(conn *grpc.ClientConn, service string) Check {
+	client := healthpb.NewHealthClient(conn)
+	return func(ctx context.Context) error {
+		res, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			return err
+		}
+		if res.Status != healthpb.HealthCheckResponse_SERVING {
+			return fmt.Errorf("service %q is %s", service, res.Status)
+		}
+		return nil
+	}
+}
+
+// ReachableCheck makes a HEAD request to url and passes on any response
+// at all, whatever its status, so that it can check that an API is
+// reachable without the credentials or the quota needed to use it.
+func ReachableCheck(url string) Check {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequest("HEAD", url, nil)
+		if err != nil {
+			return err
+		}
+		res, err := http.DefaultClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		return res.Body.Close()
+	}
+}
+
+// YouTubeAPI is the URL at which to check that the YouTube API is reachable.
+const YouTubeAPI = "https://www.googleapis.com/youtube/v3/videos"
@@ -0,0 +1,236 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package health reports whether a service is alive and ready to serve,
+// over HTTP at /healthz and /readyz and as the standard gRPC health service.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+)
+
+// Check returns an error if a dependency of the service is unusable.
+type Check func(ctx context.Context) error
+
+// Checker runs the readiness checks of a service. A service is alive as
+// long as it responds at all, and ready once every readiness check passes.
+// Degraded checks don't gate the readiness, they are only reported.
+type Checker struct {
+	// Timeout bounds each run of the readiness checks.
+	Timeout time.Duration
+
+	// GRPCRefreshInterval is how often the status of the
+	// gRPC health service is refreshed, see RegisterGRPC.
+	GRPCRefreshInterval time.Duration
+
+	mu          sync.Mutex
+	checks      map[string]Check
+	degrades    map[string]bool
+	draining    bool
+	grpcServers []*grpchealth.Server
+}
+
+// NewChecker returns a Checker without any readiness checks,
+// whose checks time out after 5s and refresh every 10s.
+func NewChecker() *Checker {
+	return &Checker{
+		Timeout:             5 * time.Second,
+		GRPCRefreshInterval: 10 * time.Second,
+		checks:              make(map[string]Check),
+		degrades:            make(map[string]bool),
+	}
+}
+
+// AddReadinessCheck adds check, reported as name, to the readiness checks.
+func (c *Checker) AddReadinessCheck(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check
+	delete(c.degrades, name)
+}
+
+// AddDegradedCheck adds check, reported as name, to the checks run with
+// the readiness checks, whose failure only reports the service degraded
+// while it stays ready, e.g. for a dependency that some requests can do without.
+func (c *Checker) AddDegradedCheck(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check
+	c.degrades[name] = true
+}
+
+// Drain makes the service unready for good, whatever the checks report,
+// so that it stops being sent traffic ahead of shutting down.
+func (c *Checker) Drain() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.draining = true
+	for _, hs := range c.grpcServers {
+		hs.Shutdown()
+	}
+}
+
+// errDraining is reported by the "draining" check once Drain is called.
+var errDraining = errors.New("shutting down")
+
+// Ready runs every readiness and degraded check concurrently
+// and returns the error of each, nil for those which passed.
+func (c *Checker) Ready(ctx context.Context) map[string]error {
+	c.mu.Lock()
+	checks := make(map[string]Check, len(c.checks)+1)
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	if c.draining {
+		checks["draining"] = func(context.Context) error { return errDraining }
+	}
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	results := make(map[string]error, len(checks))
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+			err := check(ctx)
+			mu.Lock()
+			results[name] = err
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+	return results
+}
+
+// Register serves the liveness at LivenessPath and the readiness at
+// ReadinessPath on mux, both as JSON e.g.
+//
+//	{"status": "degraded", "checks": {"mongodb": "ok", "search": "connection refused"}}
+//
+// The readiness fails with 503 only if its status is "unavailable".
+func (c *Checker) Register(mux *http.ServeMux) {
+	mux.HandleFunc(LivenessPath, func(w http.ResponseWriter, r *http.Request) {
+		writeStatus(w, http.StatusOK, statusOK, nil)
+	})
+	mux.HandleFunc(ReadinessPath, c.serveReadiness)
+}
+
+func (c *Checker) serveReadiness(w http.ResponseWriter, r *http.Request) {
+	results := c.Ready(r.Context())
+	checks := make(map[string]string, len(results))
+	for name, err := range results {
+		checks[name] = "ok"
+		if err != nil {
+			checks[name] = err.Error()
+		}
+	}
+	code, status := http.StatusOK, c.status(results)
+	if status == statusUnavailable {
+		code = http.StatusServiceUnavailable
+	}
+	writeStatus(w, code, status, checks)
+}
+
+const (
+	statusOK          = "ok"
+	statusDegraded    = "degraded"
+	statusUnavailable = "unavailable"
+)
+
+// status summarizes results, as returned by Ready: unavailable if any
+// readiness check failed, else degraded if any degraded check did.
+func (c *Checker) status(results map[string]error) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	status := statusOK
+	for name, err := range results {
+		switch {
+		case err == nil:
+		case c.degrades[name]:
+			status = statusDegraded
+		default:
+			return statusUnavailable
+		}
+	}
+	return status
+}
+
+func writeStatus(w http.ResponseWriter, code int, status string, checks map[string]string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	body := map[string]interface{}{"status": status}
+	if checks != nil {
+		body["checks"] = checks
+	}
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// IsEndpoint reports whether r is a liveness or readiness probe,
+// for ochttp.Handler.IsHealthEndpoint to not trace probes.
+func IsEndpoint(r *http.Request) bool {
+	return r.URL.Path == LivenessPath || r.URL.Path == ReadinessPath
+}
+
+// RegisterGRPC registers the standard gRPC health service on srv, reporting
+// every service registered so far as well as the server as a whole, named "",
+// as serving only while the readiness checks pass, whatever the degraded
+// checks report, and until Drain is called.
+// The checks are rerun every GRPCRefreshInterval, in the background.
+func (c *Checker) RegisterGRPC(srv *grpc.Server) *grpchealth.Server {
+	services := []string{""}
+	for name := range srv.GetServiceInfo() {
+		services = append(services, name)
+	}
+
+	hs := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+	for _, service := range services {
+		hs.SetServingStatus(service, healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+	c.mu.Lock()
+	c.grpcServers = append(c.grpcServers, hs)
+	c.mu.Unlock()
+
+	go func() {
+		for {
+			status := healthpb.HealthCheckResponse_SERVING
+			if c.status(c.Ready(context.Background())) == statusUnavailable {
+				status = healthpb.HealthCheckResponse_NOT_SERVING
+			}
+			for _, service := range services {
+				hs.SetServingStatus(service, status)
+			}
+			<-time.After(c.GRPCRefreshInterval)
+		}
+	}()
+	return hs
+}
@@ -0,0 +1,201 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package keypool spreads the calls to the YouTube API over a pool of API
+// keys, keeping track of the quota and the errors of each key and failing
+// over to the next key whenever one runs out of quota.
+package keypool
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"path"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+
+	"github.com/orijtech/media-search/secrets"
+)
+
+var (
+	// KeyAPIKey tags the metrics with the ID of the key, see KeyID.
+	KeyAPIKey = mustKey("api_key")
+	KeyResult = mustKey("result")
+
+	calls      = stats.Int64("api_key_calls", "The number of calls made with an API key", "1")
+	quotaUnits = stats.Int64("api_key_quota_units", "The estimated quota units used by an API key", "1")
+	exhausted  = stats.Int64("api_key_exhausted", "Whether an API key is out of quota: 0 no, 1 yes", "1")
+)
+
+// The results the calls are tagged with.
+const (
+	ResultOK            = "ok"
+	ResultError         = "error"
+	ResultQuotaExceeded = "quota_exceeded"
+)
+
+// Views are the views for the metrics recorded by this package.
+var Views = []*view.View{
+	{
+		Name: "api_key_calls", Description: "calls made per API key",
+		Measure: calls, Aggregation: view.Count(), TagKeys: []tag.Key{KeyAPIKey, KeyResult},
+	}, {
+		Name: "api_key_quota_units", Description: "estimated quota units used per API key",
+		Measure: quotaUnits, Aggregation: view.Sum(), TagKeys: []tag.Key{KeyAPIKey},
+	}, {
+		Name: "api_key_exhausted", Description: "whether an API key is out of quota: 0 no, 1 yes",
+		Measure: exhausted, Aggregation: view.LastValue(), TagKeys: []tag.Key{KeyAPIKey},
+	},
+}
+
+// Pool hands out the keys of a Keyring in turn, skipping those out of quota.
+type Pool struct {
+	keys *secrets.Keyring
+
+	// DailyQuota, if positive, is the number of quota units each key may
+	// use per day. A key estimated to have used them up is skipped until
+	// the quota resets, instead of waiting for YouTube to reject it.
+	DailyQuota int
+
+	mu    sync.Mutex
+	next  int
+	usage map[string]*keyUsage
+}
+
+type keyUsage struct {
+	// day is the start of the quota day that units were used in.
+	day            time.Time
+	units          int
+	exhaustedUntil time.Time
+}
+
+// New returns a Pool of the keys, including those rotated in later.
+func New(keys *secrets.Keyring) *Pool {
+	return &Pool{keys: keys, usage: make(map[string]*keyUsage)}
+}
+
+// KeyID identifies key in the metrics and logs without revealing it.
+func KeyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:4])
+}
+
+// take returns the next key, other than those tried already, that has
+// quota left for a call costing units, and charges the units to it.
+func (p *Pool) take(tried map[string]bool, units int) (string, bool) {
+	keys := p.keys.Keys()
+	now := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := range keys {
+		key := keys[(p.next+i)%len(keys)]
+		if tried[key] {
+			continue
+		}
+		ku := p.usageOf(key, now)
+		if now.Before(ku.exhaustedUntil) {
+			continue
+		}
+		if p.DailyQuota > 0 && ku.units+units > p.DailyQuota {
+			continue
+		}
+		if !ku.exhaustedUntil.IsZero() {
+			ku.exhaustedUntil = time.Time{}
+			record(key, exhausted.M(0))
+		}
+		ku.units += units
+		p.next = (p.next + i + 1) % len(keys)
+		record(key, quotaUnits.M(int64(units)))
+		return key, true
+	}
+	return "", false
+}
+
+// usageOf returns the usage of key, starting afresh every quota day.
+func (p *Pool) usageOf(key string, now time.Time) *keyUsage {
+	day := quotaDay(now)
+	ku := p.usage[key]
+	if ku == nil {
+		ku = new(keyUsage)
+		p.usage[key] = ku
+	}
+	if !ku.day.Equal(day) {
+		ku.day, ku.units = day, 0
+	}
+	return ku
+}
+
+// report records the result of a call made with key. A key
+// out of quota is skipped until its quota resets.
+func (p *Pool) report(key, result string) {
+	now := time.Now()
+	p.mu.Lock()
+	ku := p.usageOf(key, now)
+	if result == ResultQuotaExceeded {
+		ku.exhaustedUntil = ku.day.AddDate(0, 0, 1)
+	}
+	p.mu.Unlock()
+
+	ctx, _ := tag.New(context.Background(), tag.Upsert(KeyAPIKey, KeyID(key)), tag.Upsert(KeyResult, result))
+	stats.Record(ctx, calls.M(1))
+	if result == ResultQuotaExceeded {
+		record(key, exhausted.M(1))
+	}
+}
+
+func record(key string, m stats.Measurement) {
+	ctx, _ := tag.New(context.Background(), tag.Upsert(KeyAPIKey, KeyID(key)))
+	stats.Record(ctx, m)
+}
+
+// pacific is where the YouTube API quotas reset, at midnight.
+var pacific = loadPacific()
+
+func loadPacific() *time.Location {
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		// Without the time zone database, ignoring daylight
+		// saving at worst makes the reset an hour late.
+		return time.FixedZone("PST", -8*60*60)
+	}
+	return loc
+}
+
+func quotaDay(t time.Time) time.Time {
+	t = t.In(pacific)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, pacific)
+}
+
+// unitCost estimates the quota units a call to the YouTube API at urlPath
+// costs, see https://developers.google.com/youtube/v3/determine_quota_cost
+func unitCost(urlPath string) int {
+	if path.Base(urlPath) == "search" {
+		return 100
+	}
+	return 1
+}
+
+func mustKey(sk string) tag.Key {
+	k, err := tag.NewKey(sk)
+	if err != nil {
+		log.Fatalf("Creating new key %q error: %v", sk, err)
+	}
+	return k
+}
@@ -0,0 +1,191 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keypool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/orijtech/media-search/secrets"
+)
+
+func newTestPool(t *testing.T, keys ...string) *Pool {
+	kr, err := secrets.NewKeyring(secrets.Static(keys...))
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	return New(kr)
+}
+
+// takeAll takes n keys in turn, without failing over.
+func takeAll(p *Pool, n, units int) []string {
+	var got []string
+	for i := 0; i < n; i++ {
+		key, ok := p.take(nil, units)
+		if !ok {
+			key = "none"
+		}
+		got = append(got, key)
+	}
+	return got
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestTake(t *testing.T) {
+	tests := []struct {
+		name       string
+		dailyQuota int
+		units      int
+		exhausted  []string
+		want       []string
+	}{
+		{
+			name:  "round robin",
+			units: 100,
+			want:  []string{"a", "b", "c", "a", "b", "c"},
+		},
+		{
+			name:      "skips the keys out of quota",
+			units:     100,
+			exhausted: []string{"b"},
+			want:      []string{"a", "c", "a", "c"},
+		},
+		{
+			name:      "every key out of quota",
+			units:     1,
+			exhausted: []string{"a", "b", "c"},
+			want:      []string{"none", "none"},
+		},
+		{
+			name:       "daily quota",
+			dailyQuota: 250,
+			units:      100,
+			want:       []string{"a", "b", "c", "a", "b", "c", "none"},
+		},
+		{
+			name:       "daily quota left for cheaper calls",
+			dailyQuota: 2,
+			units:      1,
+			want:       []string{"a", "b", "c", "a", "b", "c", "none"},
+		},
+	}
+	for _, tt := range tests {
+		p := newTestPool(t, "a", "b", "c")
+		p.DailyQuota = tt.dailyQuota
+		for _, key := range tt.exhausted {
+			p.report(key, ResultQuotaExceeded)
+		}
+		if got := takeAll(p, len(tt.want), tt.units); !equal(got, tt.want) {
+			t.Errorf("%s: took %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTakeSkipsTried(t *testing.T) {
+	p := newTestPool(t, "a", "b", "c")
+	tried := map[string]bool{"a": true, "c": true}
+	if key, ok := p.take(tried, 1); !ok || key != "b" {
+		t.Errorf("take after trying a and c = %q, %t, want b", key, ok)
+	}
+	tried["b"] = true
+	if key, ok := p.take(tried, 1); ok {
+		t.Errorf("take after trying every key = %q, want none", key)
+	}
+}
+
+func TestQuotaResets(t *testing.T) {
+	p := newTestPool(t, "a")
+	p.DailyQuota = 100
+	if _, ok := p.take(nil, 100); !ok {
+		t.Fatal("no quota for the first call")
+	}
+	p.report("a", ResultQuotaExceeded)
+	if key, ok := p.take(nil, 1); ok {
+		t.Fatalf("took %q out of quota", key)
+	}
+
+	// Move the usage back a day, as if the quota had reset since.
+	p.mu.Lock()
+	ku := p.usage["a"]
+	ku.day = ku.day.AddDate(0, 0, -1)
+	ku.exhaustedUntil = ku.exhaustedUntil.AddDate(0, 0, -1)
+	p.mu.Unlock()
+	if _, ok := p.take(nil, 100); !ok {
+		t.Error("no quota the day after running out")
+	}
+}
+
+func TestReportKeepsOKKeys(t *testing.T) {
+	p := newTestPool(t, "a", "b")
+	p.report("a", ResultError)
+	p.report("b", ResultOK)
+	if got, want := takeAll(p, 4, 100), []string{"a", "b", "a", "b"}; !equal(got, want) {
+		t.Errorf("took %q after errors, want %q", got, want)
+	}
+}
+
+func TestQuotaDay(t *testing.T) {
+	tests := []struct {
+		t    time.Time
+		want time.Time
+	}{
+		// 07:59 UTC is still the previous day in Pacific Standard Time.
+		{time.Date(2026, time.January, 15, 7, 59, 0, 0, time.UTC), time.Date(2026, time.January, 14, 0, 0, 0, 0, pacific)},
+		{time.Date(2026, time.January, 15, 8, 0, 0, 0, time.UTC), time.Date(2026, time.January, 15, 0, 0, 0, 0, pacific)},
+		{time.Date(2026, time.January, 15, 23, 59, 0, 0, pacific), time.Date(2026, time.January, 15, 0, 0, 0, 0, pacific)},
+	}
+	for _, tt := range tests {
+		if got := quotaDay(tt.t); !got.Equal(tt.want) {
+			t.Errorf("quotaDay(%v) = %v, want %v", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestUnitCost(t *testing.T) {
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/youtube/v3/search", 100},
+		{"/youtube/v3/videos", 1},
+		{"/youtube/v3/channels", 1},
+	}
+	for _, tt := range tests {
+		if got := unitCost(tt.path); got != tt.want {
+			t.Errorf("unitCost(%q) = %d, want %d", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestKeyID(t *testing.T) {
+	id := KeyID("AIzaSyExample")
+	if len(id) != 8 {
+		t.Errorf("KeyID is %q, want 8 hex digits", id)
+	}
+	if id != KeyID("AIzaSyExample") || id == KeyID("AIzaSyExamplf") {
+		t.Error("KeyID doesn't identify a key")
+	}
+}
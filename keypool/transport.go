@@ -0,0 +1,152 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keypool
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// Transport returns a RoundTripper that sets the "key" query parameter of
+// every request to a key of the pool. When YouTube answers that the key is
+// out of quota, the request is retried with the next key, until none is
+// left. If base is nil, http.DefaultTransport is used.
+func (p *Pool) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{pool: p, base: base}
+}
+
+type transport struct {
+	pool *Pool
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	units := unitCost(req.URL.Path)
+	// Only requests whose body can be read again can be retried.
+	canRetry := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	tried := make(map[string]bool)
+	var exhaustedRes *http.Response
+	for {
+		key, ok := t.pool.take(tried, units)
+		if !ok {
+			if exhaustedRes != nil {
+				return exhaustedRes, nil
+			}
+			return quotaExceededResponse(req), nil
+		}
+		tried[key] = true
+
+		// RoundTrippers must not modify the request, so change a copy of its URL.
+		keyed := req.Clone(req.Context())
+		if len(tried) > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			keyed.Body = body
+		}
+		query := keyed.URL.Query()
+		query.Set("key", key)
+		keyed.URL.RawQuery = query.Encode()
+
+		res, err := t.base.RoundTrip(keyed)
+		result := resultOf(res, err)
+		t.pool.report(key, result)
+		if result != ResultQuotaExceeded {
+			return res, err
+		}
+
+		slog.WarnContext(req.Context(), "API key out of quota, failing over to the next one", "api_key", KeyID(key))
+		if exhaustedRes != nil {
+			exhaustedRes.Body.Close()
+		}
+		exhaustedRes = res
+		if !canRetry {
+			return res, nil
+		}
+	}
+}
+
+func resultOf(res *http.Response, err error) string {
+	switch {
+	case err != nil || res.StatusCode >= 500:
+		return ResultError
+	case res.StatusCode == http.StatusForbidden && outOfQuota(res):
+		return ResultQuotaExceeded
+	case res.StatusCode >= 400:
+		return ResultError
+	default:
+		return ResultOK
+	}
+}
+
+// outOfQuota reports whether res is a YouTube error of the form
+//
+//	{"error": {"errors": [{"reason": "quotaExceeded", ...}], ...}}
+//
+// and leaves its body to be read again.
+func outOfQuota(res *http.Response) bool {
+	blob, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	res.Body = ioutil.NopCloser(bytes.NewReader(blob))
+	if err != nil {
+		return false
+	}
+
+	var body struct {
+		Error struct {
+			Errors []struct {
+				Reason string `json:"reason"`
+			} `json:"errors"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(blob, &body); err != nil {
+		return false
+	}
+	for _, item := range body.Error.Errors {
+		switch item.Reason {
+		case "quotaExceeded", "dailyLimitExceeded":
+			return true
+		}
+	}
+	return false
+}
+
+// quotaExceededResponse answers req the way YouTube would if
+// every key was out of quota, so that callers needn't tell apart
+// the quota enforced locally from that enforced by YouTube.
+func quotaExceededResponse(req *http.Request) *http.Response {
+	const body = `{"error":{"code":403,"message":"Every API key has exceeded its quota.",` +
+		`"errors":[{"domain":"youtube.quota","reason":"quotaExceeded","message":"Every API key has exceeded its quota."}]}}`
+	return &http.Response{
+		Status:        "403 Forbidden",
+		StatusCode:    http.StatusForbidden,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json; charset=UTF-8"}},
+		Body:          ioutil.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
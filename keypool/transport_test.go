@@ -0,0 +1,185 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keypool
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const quotaExceededBody = `{"error":{"code":403,"errors":[{"domain":"youtube.quota","reason":"quotaExceeded"}]}}`
+
+// fakeYouTube answers the requests made with the keys in outOfQuota
+// as out of quota, and the others with the key and body they came with.
+type fakeYouTube struct {
+	outOfQuota map[string]bool
+	keys       []string
+}
+
+func (fy *fakeYouTube) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := req.URL.Query().Get("key")
+	fy.keys = append(fy.keys, key)
+	status, body := http.StatusOK, "key="+key
+	if req.Body != nil {
+		blob, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		if len(blob) > 0 {
+			body += " body=" + string(blob)
+		}
+	}
+	if fy.outOfQuota[key] {
+		status, body = http.StatusForbidden, quotaExceededBody
+	}
+	rec := httptest.NewRecorder()
+	rec.WriteHeader(status)
+	rec.WriteString(body)
+	return rec.Result(), nil
+}
+
+func TestTransportFailover(t *testing.T) {
+	tests := []struct {
+		name       string
+		outOfQuota []string
+		wantTried  []string
+		wantStatus int
+		wantBody   string
+	}{
+		{"first key", nil, []string{"a"}, http.StatusOK, "key=a"},
+		{"fails over", []string{"a"}, []string{"a", "b"}, http.StatusOK, "key=b"},
+		{"fails over twice", []string{"a", "b"}, []string{"a", "b", "c"}, http.StatusOK, "key=c"},
+		{"every key out of quota", []string{"a", "b", "c"}, []string{"a", "b", "c"}, http.StatusForbidden, quotaExceededBody},
+	}
+	for _, tt := range tests {
+		fy := &fakeYouTube{outOfQuota: make(map[string]bool)}
+		for _, key := range tt.outOfQuota {
+			fy.outOfQuota[key] = true
+		}
+		p := newTestPool(t, "a", "b", "c")
+		req := httptest.NewRequest("GET", "https://www.googleapis.com/youtube/v3/search?q=ocean", nil)
+		req.RequestURI = ""
+		res, err := p.Transport(fy).RoundTrip(req)
+		if err != nil {
+			t.Errorf("%s: RoundTrip: %v", tt.name, err)
+			continue
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != tt.wantStatus || string(body) != tt.wantBody {
+			t.Errorf("%s: got %d %q, want %d %q", tt.name, res.StatusCode, body, tt.wantStatus, tt.wantBody)
+		}
+		if !equal(fy.keys, tt.wantTried) {
+			t.Errorf("%s: tried %q, want %q", tt.name, fy.keys, tt.wantTried)
+		}
+		if req.URL.Query().Get("key") != "" {
+			t.Errorf("%s: the request was modified: %v", tt.name, req.URL)
+		}
+	}
+}
+
+func TestTransportLocalQuota(t *testing.T) {
+	fy := &fakeYouTube{}
+	p := newTestPool(t, "a")
+	p.DailyQuota = 100
+	rt := p.Transport(fy)
+
+	res, err := rt.RoundTrip(httptest.NewRequest("GET", "https://www.googleapis.com/youtube/v3/search?q=ocean", nil))
+	if err != nil || res.StatusCode != http.StatusOK {
+		t.Fatalf("first search: %v, %v", res, err)
+	}
+	// No quota is left for another search, so YouTube isn't even asked.
+	res, err = rt.RoundTrip(httptest.NewRequest("GET", "https://www.googleapis.com/youtube/v3/search?q=ocean", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode != http.StatusForbidden || !strings.Contains(string(body), `"reason":"quotaExceeded"`) {
+		t.Errorf("second search: got %d %q, want quotaExceeded", res.StatusCode, body)
+	}
+	if len(fy.keys) != 1 {
+		t.Errorf("made %d calls to YouTube, want 1", len(fy.keys))
+	}
+}
+
+func TestTransportRetriesBody(t *testing.T) {
+	fy := &fakeYouTube{outOfQuota: map[string]bool{"a": true}}
+	p := newTestPool(t, "a", "b")
+
+	// httptest.NewRequest, unlike http.NewRequest, doesn't set GetBody.
+	req, err := http.NewRequest("POST", "https://www.googleapis.com/youtube/v3/videos", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := p.Transport(fy).RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	if want := "key=b body=payload"; string(body) != want {
+		t.Errorf("got %q, want %q", body, want)
+	}
+
+	// Without GetBody, the body can't be sent again.
+	fy.keys = nil
+	req = httptest.NewRequest("POST", "https://www.googleapis.com/youtube/v3/videos", strings.NewReader("payload"))
+	res, err = newTestPool(t, "a", "b").Transport(fy).RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusForbidden || len(fy.keys) != 1 {
+		t.Errorf("got %d after trying %q, want 403 after trying only a", res.StatusCode, fy.keys)
+	}
+}
+
+func TestResultOf(t *testing.T) {
+	response := func(status int, body string) *http.Response {
+		rec := httptest.NewRecorder()
+		rec.WriteHeader(status)
+		rec.WriteString(body)
+		return rec.Result()
+	}
+	tests := []struct {
+		name string
+		res  *http.Response
+		err  error
+		want string
+	}{
+		{"ok", response(http.StatusOK, "{}"), nil, ResultOK},
+		{"transport error", nil, errors.New("connection reset"), ResultError},
+		{"server error", response(http.StatusServiceUnavailable, ""), nil, ResultError},
+		{"bad request", response(http.StatusBadRequest, `{"error":{"errors":[{"reason":"badRequest"}]}}`), nil, ResultError},
+		{"quota exceeded", response(http.StatusForbidden, quotaExceededBody), nil, ResultQuotaExceeded},
+		{"daily limit exceeded", response(http.StatusForbidden, `{"error":{"errors":[{"reason":"dailyLimitExceeded"}]}}`), nil, ResultQuotaExceeded},
+		{"forbidden", response(http.StatusForbidden, `{"error":{"errors":[{"reason":"forbidden"}]}}`), nil, ResultError},
+		{"forbidden, not JSON", response(http.StatusForbidden, "<html>"), nil, ResultError},
+	}
+	for _, tt := range tests {
+		if got := resultOf(tt.res, tt.err); got != tt.want {
+			t.Errorf("%s: resultOf = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// The body is left to be read by the caller.
+	res := response(http.StatusForbidden, quotaExceededBody)
+	resultOf(res, nil)
+	if body, _ := ioutil.ReadAll(res.Body); string(body) != quotaExceededBody {
+		t.Errorf("read %q after resultOf, want the whole body", body)
+	}
+}
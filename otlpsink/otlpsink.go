@@ -0,0 +1,154 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// otlpsink is a stand-in for an OpenTelemetry collector during development:
+// it accepts OTLP over gRPC and logs the spans and metrics that it receives.
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"flag"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+func main() {
+	var addr string
+	var expect string
+	flag.StringVar(&addr, "addr", ":4317", "the address on which to accept OTLP over gRPC")
+	flag.StringVar(&expect, "expect-metrics", "", "a comma separated list of metric names to report on until all have been received")
+	flag.Parse()
+
+	s := &sink{seenMetrics: make(map[string]bool)}
+	for _, name := range strings.Split(expect, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			s.expectedMetrics = append(s.expectedMetrics, name)
+		}
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("Failed to listen on address %q error: %v", addr, err)
+	}
+	srv := grpc.NewServer()
+	collectortrace.RegisterTraceServiceServer(srv, &traceSink{sink: s})
+	collectormetrics.RegisterMetricsServiceServer(srv, &metricsSink{sink: s})
+
+	log.Printf("Accepting OTLP at %q", addr)
+	if err := srv.Serve(ln); err != nil {
+		log.Fatalf("gRPC server Serve error: %v", err)
+	}
+}
+
+type sink struct {
+	mu              sync.Mutex
+	seenMetrics     map[string]bool
+	expectedMetrics []string
+}
+
+// sawMetric logs every metric name the first time that it is received and,
+// if it is one of the expected ones, which of those are still missing.
+func (s *sink) sawMetric(service, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.seenMetrics[name] {
+		return
+	}
+	s.seenMetrics[name] = true
+	log.Printf("metric: service=%q name=%q", service, name)
+
+	wasExpected := false
+	var missing []string
+	for _, expected := range s.expectedMetrics {
+		if expected == name {
+			wasExpected = true
+		}
+		if !s.seenMetrics[expected] {
+			missing = append(missing, expected)
+		}
+	}
+	if !wasExpected {
+		return
+	}
+	if len(missing) == 0 {
+		log.Printf("Received all %d expected metrics", len(s.expectedMetrics))
+	} else {
+		sort.Strings(missing)
+		log.Printf("Still missing metrics: %v", missing)
+	}
+}
+
+type traceSink struct {
+	collectortrace.UnimplementedTraceServiceServer
+	sink *sink
+}
+
+func (ts *traceSink) Export(ctx context.Context, req *collectortrace.ExportTraceServiceRequest) (*collectortrace.ExportTraceServiceResponse, error) {
+	for _, rs := range req.ResourceSpans {
+		service := serviceName(rs.Resource)
+		for _, ss := range rs.ScopeSpans {
+			for _, span := range ss.Spans {
+				latency := time.Duration(span.EndTimeUnixNano - span.StartTimeUnixNano)
+				log.Printf("span: service=%q name=%q trace=%s span=%s parent=%s latency=%s",
+					service, span.Name, hex.EncodeToString(span.TraceId), hex.EncodeToString(span.SpanId),
+					hex.EncodeToString(span.ParentSpanId), latency)
+			}
+		}
+	}
+	return new(collectortrace.ExportTraceServiceResponse), nil
+}
+
+type metricsSink struct {
+	collectormetrics.UnimplementedMetricsServiceServer
+	sink *sink
+}
+
+func (ms *metricsSink) Export(ctx context.Context, req *collectormetrics.ExportMetricsServiceRequest) (*collectormetrics.ExportMetricsServiceResponse, error) {
+	for _, rm := range req.ResourceMetrics {
+		service := serviceName(rm.Resource)
+		for _, sm := range rm.ScopeMetrics {
+			for _, metric := range sm.Metrics {
+				ms.sink.sawMetric(service, metric.Name)
+			}
+		}
+	}
+	return new(collectormetrics.ExportMetricsServiceResponse), nil
+}
+
+func serviceName(res *resourcepb.Resource) string {
+	if res == nil {
+		return ""
+	}
+	for _, kv := range res.Attributes {
+		if kv.Key == "service.name" {
+			if sv, ok := kv.Value.Value.(*commonpb.AnyValue_StringValue); ok {
+				return sv.StringValue
+			}
+		}
+	}
+	return ""
+}
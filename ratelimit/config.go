@@ -0,0 +1,83 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"fmt"
+
+	"github.com/orijtech/media-search/config"
+)
+
+// Config holds the limits per client of the requests served from the
+// caches, the hits, and of those that go to the YouTube API, the misses.
+// Every request counts against the hits limit, a miss against both.
+// Before that, every request counts against the limit of its IP address,
+// the ips, authenticated or not. A rate of 0 disables its limit.
+type Config struct {
+	HitRate   float64
+	HitBurst  int
+	MissRate  float64
+	MissBurst int
+	IPRate    float64
+	IPBurst   int
+}
+
+// Fields returns the settings of c, to be loaded by a config.Loader.
+func (c *Config) Fields() []*config.Field {
+	return []*config.Field{
+		{Name: "rate-limit-hits", Env: "MEDIA_SEARCH_RATE_LIMIT_HITS", Usage: "the requests per second allowed to each client, 0 for unlimited", Value: &c.HitRate},
+		{Name: "rate-limit-hits-burst", Env: "MEDIA_SEARCH_RATE_LIMIT_HITS_BURST", Usage: "the requests each client may make at once", Value: &c.HitBurst},
+		{Name: "rate-limit-misses", Env: "MEDIA_SEARCH_RATE_LIMIT_MISSES", Usage: "the cache misses per second allowed to each client, 0 for unlimited", Value: &c.MissRate},
+		{Name: "rate-limit-misses-burst", Env: "MEDIA_SEARCH_RATE_LIMIT_MISSES_BURST", Usage: "the cache misses each client may cause at once", Value: &c.MissBurst},
+		{Name: "rate-limit-ips", Env: "MEDIA_SEARCH_RATE_LIMIT_IPS", Usage: "the requests per second allowed from each IP address before authentication, 0 for unlimited", Value: &c.IPRate},
+		{Name: "rate-limit-ips-burst", Env: "MEDIA_SEARCH_RATE_LIMIT_IPS_BURST", Usage: "the requests each IP address may make at once", Value: &c.IPBurst},
+	}
+}
+
+// Validate returns an error if a rate is negative or a burst of a limit isn't positive.
+func (c *Config) Validate() error {
+	if err := checkLimit("rate-limit-hits", c.HitRate, c.HitBurst); err != nil {
+		return err
+	}
+	if err := checkLimit("rate-limit-misses", c.MissRate, c.MissBurst); err != nil {
+		return err
+	}
+	return checkLimit("rate-limit-ips", c.IPRate, c.IPBurst)
+}
+
+func checkLimit(name string, rate float64, burst int) error {
+	if rate < 0 {
+		return fmt.Errorf("config: %s: %g is negative", name, rate)
+	}
+	if rate > 0 && burst < 1 {
+		return fmt.Errorf("config: %s-burst: %d is not positive", name, burst)
+	}
+	return nil
+}
+
+// Hits returns the Limiter of the hits, nil if unlimited.
+func (c *Config) Hits() *Limiter {
+	return NewLimiter("hits", c.HitRate, c.HitBurst)
+}
+
+// Misses returns the Limiter of the misses, nil if unlimited.
+func (c *Config) Misses() *Limiter {
+	return NewLimiter("misses", c.MissRate, c.MissBurst)
+}
+
+// IPs returns the Limiter of the IP addresses, nil if unlimited.
+func (c *Config) IPs() *Limiter {
+	return NewLimiter("ips", c.IPRate, c.IPBurst)
+}
@@ -0,0 +1,200 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ratelimit throttles each client of the frontend with a token
+// bucket, so that no single client can use up the YouTube API quota.
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.opencensus.io/trace"
+
+	"github.com/orijtech/media-search/auth"
+	"github.com/orijtech/media-search/rpc"
+)
+
+var (
+	KeyLimit = mustKey("limit")
+
+	throttled = stats.Int64("throttled_requests", "The number of requests rejected for exceeding a rate limit", "1")
+)
+
+// Views are the views for the metrics recorded by this package.
+var Views = []*view.View{
+	{
+		Name: "throttled_requests", Description: "requests rejected for exceeding a rate limit",
+		Measure: throttled, Aggregation: view.Count(), TagKeys: []tag.Key{KeyLimit, auth.KeyPrincipal},
+	},
+}
+
+// sweepInterval is how often the buckets that have filled
+// up again, which are as good as new, are dropped.
+const sweepInterval = time.Minute
+
+// Limiter allows each client rate requests per second on average, in
+// bursts of up to burst requests. A nil *Limiter allows every request.
+type Limiter struct {
+	name  string
+	rate  float64
+	burst float64
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter returns a Limiter named name, the value of its KeyLimit tag,
+// or nil, allowing every request, if rate isn't positive.
+func NewLimiter(name string, rate float64, burst int) *Limiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		name:      name,
+		rate:      rate,
+		burst:     float64(burst),
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow takes a token from the bucket of client. If there is none
+// left, it returns false and how long until there will be one.
+func (l *Limiter) Allow(client string) (ok bool, retryAfter time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+	l.lastSweep = now
+}
+
+// Throttle takes a token for client and, if there is none left, replies
+// RATE_LIMITED with a Retry-After header and returns true.
+func (l *Limiter) Throttle(ctx context.Context, w http.ResponseWriter, client string) bool {
+	ok, retryAfter := l.Allow(client)
+	if ok {
+		return false
+	}
+
+	tctx, _ := tag.New(ctx, tag.Upsert(KeyLimit, l.name))
+	stats.Record(tctx, throttled.M(1))
+	trace.FromContext(ctx).Annotate([]trace.Attribute{
+		trace.StringAttribute("limit", l.name),
+		trace.StringAttribute("retry_after", retryAfter.String()),
+	}, "Throttled")
+
+	// Retry-After is in whole seconds, rounded up not to retry too early.
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	rpc.WriteHTTPError(w, rpc.Errorf(rpc.CodeRateLimited, "Exceeded the %s rate limit of %g per second, retry in %v",
+		l.name, l.rate, retryAfter.Round(time.Millisecond)))
+	return true
+}
+
+// Limit throttles every request to h, by the client that made it, see ClientKey.
+func (l *Limiter) Limit(h http.Handler) http.Handler {
+	if l == nil {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l.Throttle(r.Context(), w, ClientKey(r)) {
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// LimitByIP throttles every request to h by the IP address that it came
+// from, whether or not it is authenticated, to be put in front of the
+// authentication so that failing it is throttled too.
+func (l *Limiter) LimitByIP(h http.Handler) http.Handler {
+	if l == nil {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l.Throttle(r.Context(), w, ipKey(r)) {
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// ClientKey identifies the client that made r by its principal if it was
+// authenticated, see auth.FromContext, otherwise by its IP address.
+func ClientKey(r *http.Request) string {
+	if p := auth.FromContext(r.Context()); p != nil {
+		return p.Method + ":" + p.ID
+	}
+	return ipKey(r)
+}
+
+func ipKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func mustKey(sk string) tag.Key {
+	k, err := tag.NewKey(sk)
+	if err != nil {
+		log.Fatalf("Creating new key %q error: %v", sk, err)
+	}
+	return k
+}
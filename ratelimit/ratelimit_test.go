@@ -0,0 +1,235 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/orijtech/media-search/auth"
+)
+
+func TestAllowBurst(t *testing.T) {
+	l := NewLimiter("test", 1, 3)
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("alice"); !ok {
+			t.Fatalf("request #%d of the burst of 3 was throttled", i)
+		}
+	}
+	ok, retryAfter := l.Allow("alice")
+	if ok {
+		t.Fatal("request after the burst of 3 was allowed")
+	}
+	if retryAfter <= 0 || retryAfter > time.Second {
+		t.Errorf("retry after %v, want within a second at 1 per second", retryAfter)
+	}
+	// Each client has a bucket of its own.
+	if ok, _ := l.Allow("bob"); !ok {
+		t.Error("first request of another client was throttled")
+	}
+}
+
+func TestAllowRefill(t *testing.T) {
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		want    int
+	}{
+		{"no time", 0, 0},
+		{"half a token", 250 * time.Millisecond, 0},
+		{"one token", 500 * time.Millisecond, 1},
+		{"three tokens", 1500 * time.Millisecond, 3},
+		{"capped at the burst", time.Hour, 4},
+	}
+	for _, tt := range tests {
+		l := NewLimiter("test", 2, 4)
+		for i := 0; i < 4; i++ {
+			l.Allow("alice")
+		}
+		// Wind back the bucket rather than sleep.
+		l.mu.Lock()
+		l.buckets["alice"].last = l.buckets["alice"].last.Add(-tt.elapsed)
+		l.mu.Unlock()
+
+		got := 0
+		for ok, _ := l.Allow("alice"); ok; ok, _ = l.Allow("alice") {
+			got++
+		}
+		if got != tt.want {
+			t.Errorf("%s: allowed %d requests after %v, want %d", tt.name, got, tt.elapsed, tt.want)
+		}
+	}
+}
+
+func TestSweep(t *testing.T) {
+	l := NewLimiter("test", 1, 2)
+	l.Allow("full")
+	l.Allow("empty")
+	l.Allow("empty")
+
+	l.mu.Lock()
+	l.buckets["full"].last = l.buckets["full"].last.Add(-time.Second)
+	l.sweep(time.Now())
+	_, full := l.buckets["full"]
+	_, empty := l.buckets["empty"]
+	l.mu.Unlock()
+	if full {
+		t.Error("the bucket that filled up again wasn't swept")
+	}
+	if !empty {
+		t.Error("the empty bucket was swept")
+	}
+}
+
+func TestNilLimiter(t *testing.T) {
+	if l := NewLimiter("test", 0, 10); l != nil {
+		t.Fatalf("NewLimiter with a rate of 0 = %+v, want nil", l)
+	}
+	var l *Limiter
+	for i := 0; i < 100; i++ {
+		if ok, _ := l.Allow("alice"); !ok {
+			t.Fatalf("nil Limiter throttled request #%d", i)
+		}
+	}
+	served := 0
+	h := l.LimitByIP(l.Limit(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { served++ })))
+	for i := 0; i < 100; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/search", nil))
+	}
+	if served != 100 {
+		t.Errorf("nil Limiter served %d of 100 requests", served)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	l := NewLimiter("misses", 0.5, 1)
+	if l.Throttle(context.Background(), httptest.NewRecorder(), "alice") {
+		t.Fatal("first request was throttled")
+	}
+
+	rec := httptest.NewRecorder()
+	if !l.Throttle(context.Background(), rec, "alice") {
+		t.Fatal("request beyond the burst wasn't throttled")
+	}
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("got status %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	// A token every 2 seconds, rounded up to whole seconds.
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("got Retry-After %q, want \"2\"", got)
+	}
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding the error: %v", err)
+	}
+	if body.Error.Code != "RATE_LIMITED" {
+		t.Errorf("got error code %q, want RATE_LIMITED", body.Error.Code)
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		principal  *auth.Principal
+		want       string
+	}{
+		{"IPv4", "192.0.2.1:1234", nil, "ip:192.0.2.1"},
+		{"IPv6", "[2001:db8::1]:1234", nil, "ip:2001:db8::1"},
+		{"without a port", "192.0.2.1", nil, "ip:192.0.2.1"},
+		{"API key", "192.0.2.1:1234", &auth.Principal{ID: "alice", Method: auth.MethodAPIKey}, "api_key:alice"},
+		{"JWT", "192.0.2.1:1234", &auth.Principal{ID: "alice", Method: auth.MethodJWT}, "jwt:alice"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/search", nil)
+		r.RemoteAddr = tt.remoteAddr
+		if tt.principal != nil {
+			r = r.WithContext(auth.NewContext(r.Context(), tt.principal))
+		}
+		if got := ClientKey(r); got != tt.want {
+			t.Errorf("%s: ClientKey = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit func(*Limiter, http.Handler) http.Handler
+		// Whether the second request, authenticated as another
+		// principal from the same address, is throttled.
+		wantThrottled bool
+	}{
+		{"by client", (*Limiter).Limit, false},
+		{"by IP", (*Limiter).LimitByIP, true},
+	}
+	for _, tt := range tests {
+		h := tt.limit(NewLimiter("test", 1, 1), http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+		for i, id := range []string{"alice", "bob"} {
+			r := httptest.NewRequest("GET", "/search", nil)
+			r.RemoteAddr = "192.0.2.1:" + strconv.Itoa(1234+i)
+			r = r.WithContext(auth.NewContext(r.Context(), &auth.Principal{ID: id, Method: auth.MethodAPIKey}))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+
+			wantStatus := http.StatusOK
+			if i == 1 && tt.wantThrottled {
+				wantStatus = http.StatusTooManyRequests
+			}
+			if rec.Code != wantStatus {
+				t.Errorf("%s: request of %s got status %d, want %d", tt.name, id, rec.Code, wantStatus)
+			}
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       Config
+		wantErr bool
+	}{
+		{"unlimited", Config{}, false},
+		{"limited", Config{HitRate: 10, HitBurst: 20, MissRate: 1, MissBurst: 5, IPRate: 20, IPBurst: 40}, false},
+		{"unlimited without a burst", Config{HitRate: 0, HitBurst: 0}, false},
+		{"negative rate", Config{MissRate: -1, MissBurst: 5}, true},
+		{"no burst", Config{HitRate: 10}, true},
+		{"negative burst", Config{IPRate: 10, IPBurst: -1}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.c.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() = %v, want an error: %t", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestConfigLimiters(t *testing.T) {
+	c := Config{HitRate: 10, HitBurst: 20}
+	if c.Hits() == nil {
+		t.Error("Hits() of a positive rate is nil")
+	}
+	if c.Misses() != nil || c.IPs() != nil {
+		t.Error("the Limiter of a rate of 0 isn't nil")
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+)
+
+// State is the state of a circuit breaker. Its
+// value is what is exported as the state metric.
+type State int64
+
+const (
+	StateClosed   State = 0
+	StateHalfOpen State = 1
+	StateOpen     State = 2
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half-open"
+	case StateOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrBreakerOpen is returned, without calling upstream,
+// while the circuit breaker for that upstream is open.
+var ErrBreakerOpen = errors.New("circuit breaker is open")
+
+// Breaker stops calling an upstream after FailureThreshold consecutive
+// failures. After Cooldown it lets a single trial call through: if that
+// succeeds the breaker closes again, otherwise it stays open for another Cooldown.
+type Breaker struct {
+	upstream         string
+	failureThreshold int
+	cooldown         time.Duration
+
+	// IsFailure reports whether an error counts against the upstream.
+	// If nil, every error does.
+	IsFailure func(error) bool
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	trialing bool
+}
+
+func NewBreaker(upstream string, failureThreshold int, cooldown time.Duration) *Breaker {
+	if failureThreshold <= 0 {
+		failureThreshold = 5
+	}
+	if cooldown <= 0 {
+		cooldown = 30 * time.Second
+	}
+	b := &Breaker{
+		upstream:         upstream,
+		failureThreshold: failureThreshold,
+		cooldown:         cooldown,
+	}
+	b.recordState(StateClosed)
+	return b
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Do invokes fn unless the breaker is open,
+// in which case it returns ErrBreakerOpen.
+func (b *Breaker) Do(ctx context.Context, fn func(context.Context) error) error {
+	if !b.allow() {
+		ctx, _ = tag.New(ctx, tag.Upsert(KeyUpstream, b.upstream))
+		stats.Record(ctx, breakerRejections.M(1))
+		return ErrBreakerOpen
+	}
+	err := fn(ctx)
+	b.done(err)
+	return err
+}
+
+func (b *Breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(StateHalfOpen)
+		b.trialing = true
+		return true
+
+	case StateHalfOpen:
+		// Only the single trial call is let through.
+		if b.trialing {
+			return false
+		}
+		b.trialing = true
+		return true
+
+	default:
+		return true
+	}
+}
+
+func (b *Breaker) done(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	failed := err != nil && (b.IsFailure == nil || b.IsFailure(err))
+	if b.state == StateHalfOpen {
+		b.trialing = false
+		if failed {
+			b.open()
+		} else {
+			b.failures = 0
+			b.setState(StateClosed)
+		}
+		return
+	}
+
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures += 1
+	if b.failures >= b.failureThreshold {
+		b.open()
+	}
+}
+
+func (b *Breaker) open() {
+	b.openedAt = time.Now()
+	b.setState(StateOpen)
+}
+
+// setState must be invoked with b.mu held.
+func (b *Breaker) setState(state State) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	b.recordState(state)
+}
+
+func (b *Breaker) recordState(state State) {
+	ctx, _ := tag.New(context.Background(), tag.Upsert(KeyUpstream, b.upstream))
+	stats.Record(ctx, breakerState.M(int64(state)))
+}
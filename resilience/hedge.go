@@ -0,0 +1,82 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resilience
+
+import (
+	"context"
+	"time"
+)
+
+// Hedge invokes fn and, each time delay passes without any call having
+// returned, invokes it again concurrently until maxCalls are in flight.
+// The first successful result wins and the outstanding calls are canceled.
+// If every call fails, the last error is returned.
+//
+// onHedge, if set, is invoked for every extra call made.
+func Hedge(ctx context.Context, delay time.Duration, maxCalls int, fn func(context.Context) (interface{}, error), onHedge func()) (interface{}, error) {
+	if delay <= 0 || maxCalls <= 1 {
+		return fn(ctx)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		value interface{}
+		err   error
+	}
+	// Buffered so that the losing calls never block once we've returned.
+	results := make(chan *result, maxCalls)
+	call := func() {
+		go func() {
+			value, err := fn(ctx)
+			results <- &result{value: value, err: err}
+		}()
+	}
+
+	call()
+	launched, inflight := 1, 1
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var lastErr error
+	for {
+		select {
+		case res := <-results:
+			inflight -= 1
+			if res.err == nil {
+				return res.value, nil
+			}
+			lastErr = res.err
+			if inflight == 0 {
+				return nil, lastErr
+			}
+
+		case <-timer.C:
+			if launched < maxCalls {
+				if onHedge != nil {
+					onHedge()
+				}
+				call()
+				launched += 1
+				inflight += 1
+				timer.Reset(delay)
+			}
+
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
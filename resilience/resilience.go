@@ -0,0 +1,138 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package resilience provides retries, circuit breaking and hedging
+// for calls to upstreams such as the YouTube API.
+package resilience
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.opencensus.io/trace"
+)
+
+var (
+	KeyUpstream = mustKey("upstream")
+
+	retries           = stats.Int64("upstream_retries", "The number of retried upstream calls", "1")
+	hedgedCalls       = stats.Int64("upstream_hedged_calls", "The number of hedged upstream calls", "1")
+	breakerState      = stats.Int64("circuit_breaker_state", "The circuit breaker state: 0 closed, 1 half-open, 2 open", "1")
+	breakerRejections = stats.Int64("circuit_breaker_rejections", "The number of calls rejected by an open circuit breaker", "1")
+)
+
+// Views are the views for the metrics recorded by this package.
+var Views = []*view.View{
+	{
+		Name: "upstream_retries", Description: "retried upstream calls",
+		Measure: retries, Aggregation: view.Count(), TagKeys: []tag.Key{KeyUpstream},
+	}, {
+		Name: "upstream_hedged_calls", Description: "hedged upstream calls",
+		Measure: hedgedCalls, Aggregation: view.Count(), TagKeys: []tag.Key{KeyUpstream},
+	}, {
+		Name: "circuit_breaker_state", Description: "circuit breaker state: 0 closed, 1 half-open, 2 open",
+		Measure: breakerState, Aggregation: view.LastValue(), TagKeys: []tag.Key{KeyUpstream},
+	}, {
+		Name: "circuit_breaker_rejections", Description: "calls rejected by an open circuit breaker",
+		Measure: breakerRejections, Aggregation: view.Count(), TagKeys: []tag.Key{KeyUpstream},
+	},
+}
+
+// Upstream applies the resilience policies configured
+// for a single upstream to every call made to it.
+type Upstream struct {
+	Name string
+
+	// Retry is applied outermost, around the breaker.
+	Retry   *RetryPolicy
+	Breaker *Breaker
+
+	// If HedgeDelay is positive, up to MaxHedgedCalls calls
+	// are made concurrently for every attempt, see Hedge.
+	HedgeDelay     time.Duration
+	MaxHedgedCalls int
+}
+
+// NewUpstream returns an Upstream with the default retry policy and a circuit
+// breaker. Both consider only errors for which isTransient returns true.
+func NewUpstream(name string, isTransient func(error) bool) *Upstream {
+	retry := DefaultRetryPolicy()
+	retry.Retryable = isTransient
+	breaker := NewBreaker(name, 5, 30*time.Second)
+	breaker.IsFailure = isTransient
+	return &Upstream{
+		Name:    name,
+		Retry:   retry,
+		Breaker: breaker,
+	}
+}
+
+// Call invokes fn, an idempotent call to the upstream, under the configured policies.
+func (u *Upstream) Call(ctx context.Context, fn func(context.Context) (interface{}, error)) (interface{}, error) {
+	ctx, span := trace.StartSpan(ctx, "upstream-"+u.Name)
+	defer span.End()
+
+	ctx, err := tag.New(ctx, tag.Upsert(KeyUpstream, u.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	attempt := func(ctx context.Context) error {
+		hedged := func(ctx context.Context) (err error) {
+			value, err = Hedge(ctx, u.HedgeDelay, u.MaxHedgedCalls, fn, func() {
+				stats.Record(ctx, hedgedCalls.M(1))
+			})
+			return err
+		}
+		if u.Breaker == nil {
+			return hedged(ctx)
+		}
+		return u.Breaker.Do(ctx, hedged)
+	}
+
+	retry := u.Retry
+	if retry == nil {
+		retry = &RetryPolicy{MaxAttempts: 1}
+	}
+	// There is no point retrying while the breaker is open.
+	notWhileOpen := *retry
+	notWhileOpen.Retryable = func(err error) bool {
+		return err != ErrBreakerOpen && retry.retryable(err)
+	}
+
+	err = notWhileOpen.Do(ctx, attempt, func(n int, err error) {
+		stats.Record(ctx, retries.M(1))
+		span.Annotate([]trace.Attribute{
+			trace.Int64Attribute("attempt", int64(n)),
+			trace.StringAttribute("error", err.Error()),
+		}, "Retrying upstream call")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func mustKey(sk string) tag.Key {
+	k, err := tag.NewKey(sk)
+	if err != nil {
+		log.Fatalf("Creating new key %q error: %v", sk, err)
+	}
+	return k
+}
@@ -0,0 +1,87 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resilience
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy retries idempotent calls with exponential backoff and full jitter.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of calls made, including the first one.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+
+	// Retryable reports whether a failed call is worth retrying.
+	// If nil, every error is retried.
+	Retryable func(error) bool
+}
+
+// DefaultRetryPolicy returns the policy used when none is configured.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    2 * time.Second,
+	}
+}
+
+// Do invokes fn until it succeeds, returns a non-retryable error,
+// the attempts are exhausted or ctx is done. onRetry, if set,
+// is invoked before every retry.
+func (rp *RetryPolicy) Do(ctx context.Context, fn func(context.Context) error, onRetry func(attempt int, err error)) error {
+	maxAttempts := 1
+	if rp != nil && rp.MaxAttempts > 1 {
+		maxAttempts = rp.MaxAttempts
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+		if attempt >= maxAttempts || !rp.retryable(err) {
+			return err
+		}
+		if onRetry != nil {
+			onRetry(attempt, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(rp.backoff(attempt)):
+		}
+	}
+}
+
+func (rp *RetryPolicy) retryable(err error) bool {
+	return rp.Retryable == nil || rp.Retryable(err)
+}
+
+// backoff returns a random delay in [0, min(MaxDelay, BaseDelay*2^(attempt-1))).
+func (rp *RetryPolicy) backoff(attempt int) time.Duration {
+	ceiling := rp.BaseDelay << uint(attempt-1)
+	if ceiling <= 0 || (rp.MaxDelay > 0 && ceiling > rp.MaxDelay) {
+		ceiling = rp.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)))
+}
@@ -12,17 +12,27 @@ It has these top-level messages:
 	Nothing
 	Query
 	SearchResult
+	ErrorDetail
 	YouTubeResult
 	YouTubeSnippet
 	Thumbnail
 	YouTubeID
 	SearchResults
+	TrendingQuery
+	TrendingResults
+	IDsRequest
+	IDs
+	IDRequest
+	DetailRequest
+	ErrorResponse
 */
 package rpc
 
 import proto "github.com/golang/protobuf/proto"
 import fmt "fmt"
 import math "math"
+import _ "google.golang.org/genproto/googleapis/api/annotations"
+import _ "github.com/grpc-ecosystem/grpc-gateway/protoc-gen-swagger/options"
 
 import (
 	context "golang.org/x/net/context"
@@ -100,6 +110,7 @@ type SearchResult struct {
 	Index uint64           `protobuf:"varint,1,opt,name=index" json:"index,omitempty"`
 	Items []*YouTubeResult `protobuf:"bytes,2,rep,name=items" json:"items,omitempty"`
 	Err   string           `protobuf:"bytes,3,opt,name=err" json:"err,omitempty"`
+	Error *ErrorDetail     `protobuf:"bytes,4,opt,name=error" json:"error,omitempty"`
 }
 
 func (m *SearchResult) Reset()                    { *m = SearchResult{} }
@@ -128,6 +139,37 @@ func (m *SearchResult) GetErr() string {
 	return ""
 }
 
+func (m *SearchResult) GetError() *ErrorDetail {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
+type ErrorDetail struct {
+	Code    string `protobuf:"bytes,1,opt,name=code" json:"code,omitempty"`
+	Message string `protobuf:"bytes,2,opt,name=message" json:"message,omitempty"`
+}
+
+func (m *ErrorDetail) Reset()                    { *m = ErrorDetail{} }
+func (m *ErrorDetail) String() string            { return proto.CompactTextString(m) }
+func (*ErrorDetail) ProtoMessage()               {}
+func (*ErrorDetail) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *ErrorDetail) GetCode() string {
+	if m != nil {
+		return m.Code
+	}
+	return ""
+}
+
+func (m *ErrorDetail) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
 type YouTubeResult struct {
 	ItemId  *ID             `protobuf:"bytes,1,opt,name=itemId" json:"itemId,omitempty"`
 	Etag    string          `protobuf:"bytes,2,opt,name=etag" json:"etag,omitempty"`
@@ -139,7 +181,7 @@ type YouTubeResult struct {
 func (m *YouTubeResult) Reset()                    { *m = YouTubeResult{} }
 func (m *YouTubeResult) String() string            { return proto.CompactTextString(m) }
 func (*YouTubeResult) ProtoMessage()               {}
-func (*YouTubeResult) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+func (*YouTubeResult) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
 
 func (m *YouTubeResult) GetItemId() *ID {
 	if m != nil {
@@ -188,7 +230,7 @@ type YouTubeSnippet struct {
 func (m *YouTubeSnippet) Reset()                    { *m = YouTubeSnippet{} }
 func (m *YouTubeSnippet) String() string            { return proto.CompactTextString(m) }
 func (*YouTubeSnippet) ProtoMessage()               {}
-func (*YouTubeSnippet) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+func (*YouTubeSnippet) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
 
 func (m *YouTubeSnippet) GetChannelId() string {
 	if m != nil {
@@ -241,7 +283,7 @@ type Thumbnail struct {
 func (m *Thumbnail) Reset()                    { *m = Thumbnail{} }
 func (m *Thumbnail) String() string            { return proto.CompactTextString(m) }
 func (*Thumbnail) ProtoMessage()               {}
-func (*Thumbnail) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+func (*Thumbnail) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
 
 func (m *Thumbnail) GetHeight() int64 {
 	if m != nil {
@@ -273,7 +315,7 @@ type YouTubeID struct {
 func (m *YouTubeID) Reset()                    { *m = YouTubeID{} }
 func (m *YouTubeID) String() string            { return proto.CompactTextString(m) }
 func (*YouTubeID) ProtoMessage()               {}
-func (*YouTubeID) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+func (*YouTubeID) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
 
 func (m *YouTubeID) GetKind() string {
 	if m != nil {
@@ -303,7 +345,7 @@ type SearchResults struct {
 func (m *SearchResults) Reset()                    { *m = SearchResults{} }
 func (m *SearchResults) String() string            { return proto.CompactTextString(m) }
 func (*SearchResults) ProtoMessage()               {}
-func (*SearchResults) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+func (*SearchResults) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
 
 func (m *SearchResults) GetResults() []*SearchResult {
 	if m != nil {
@@ -312,16 +354,176 @@ func (m *SearchResults) GetResults() []*SearchResult {
 	return nil
 }
 
+type TrendingQuery struct {
+	RegionCode string `protobuf:"bytes,1,opt,name=regionCode" json:"regionCode,omitempty"`
+	CategoryId string `protobuf:"bytes,2,opt,name=categoryId" json:"categoryId,omitempty"`
+	MaxResults int32  `protobuf:"varint,3,opt,name=maxResults" json:"maxResults,omitempty"`
+}
+
+func (m *TrendingQuery) Reset()                    { *m = TrendingQuery{} }
+func (m *TrendingQuery) String() string            { return proto.CompactTextString(m) }
+func (*TrendingQuery) ProtoMessage()               {}
+func (*TrendingQuery) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *TrendingQuery) GetRegionCode() string {
+	if m != nil {
+		return m.RegionCode
+	}
+	return ""
+}
+
+func (m *TrendingQuery) GetCategoryId() string {
+	if m != nil {
+		return m.CategoryId
+	}
+	return ""
+}
+
+func (m *TrendingQuery) GetMaxResults() int32 {
+	if m != nil {
+		return m.MaxResults
+	}
+	return 0
+}
+
+type TrendingResults struct {
+	RegionCode string           `protobuf:"bytes,1,opt,name=regionCode" json:"regionCode,omitempty"`
+	CategoryId string           `protobuf:"bytes,2,opt,name=categoryId" json:"categoryId,omitempty"`
+	Items      []*YouTubeResult `protobuf:"bytes,3,rep,name=items" json:"items,omitempty"`
+}
+
+func (m *TrendingResults) Reset()                    { *m = TrendingResults{} }
+func (m *TrendingResults) String() string            { return proto.CompactTextString(m) }
+func (*TrendingResults) ProtoMessage()               {}
+func (*TrendingResults) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *TrendingResults) GetRegionCode() string {
+	if m != nil {
+		return m.RegionCode
+	}
+	return ""
+}
+
+func (m *TrendingResults) GetCategoryId() string {
+	if m != nil {
+		return m.CategoryId
+	}
+	return ""
+}
+
+func (m *TrendingResults) GetItems() []*YouTubeResult {
+	if m != nil {
+		return m.Items
+	}
+	return nil
+}
+
+type IDsRequest struct {
+	Count  int32  `protobuf:"varint,1,opt,name=count" json:"count,omitempty"`
+	Scheme string `protobuf:"bytes,2,opt,name=scheme" json:"scheme,omitempty"`
+}
+
+func (m *IDsRequest) Reset()                    { *m = IDsRequest{} }
+func (m *IDsRequest) String() string            { return proto.CompactTextString(m) }
+func (*IDsRequest) ProtoMessage()               {}
+func (*IDsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *IDsRequest) GetCount() int32 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *IDsRequest) GetScheme() string {
+	if m != nil {
+		return m.Scheme
+	}
+	return ""
+}
+
+type IDs struct {
+	Values []string `protobuf:"bytes,1,rep,name=values" json:"values,omitempty"`
+}
+
+func (m *IDs) Reset()                    { *m = IDs{} }
+func (m *IDs) String() string            { return proto.CompactTextString(m) }
+func (*IDs) ProtoMessage()               {}
+func (*IDs) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *IDs) GetValues() []string {
+	if m != nil {
+		return m.Values
+	}
+	return nil
+}
+
+type IDRequest struct {
+	Scheme string `protobuf:"bytes,1,opt,name=scheme" json:"scheme,omitempty"`
+}
+
+func (m *IDRequest) Reset()                    { *m = IDRequest{} }
+func (m *IDRequest) String() string            { return proto.CompactTextString(m) }
+func (*IDRequest) ProtoMessage()               {}
+func (*IDRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *IDRequest) GetScheme() string {
+	if m != nil {
+		return m.Scheme
+	}
+	return ""
+}
+
+type DetailRequest struct {
+	Ids []string `protobuf:"bytes,1,rep,name=ids" json:"ids,omitempty"`
+}
+
+func (m *DetailRequest) Reset()                    { *m = DetailRequest{} }
+func (m *DetailRequest) String() string            { return proto.CompactTextString(m) }
+func (*DetailRequest) ProtoMessage()               {}
+func (*DetailRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+func (m *DetailRequest) GetIds() []string {
+	if m != nil {
+		return m.Ids
+	}
+	return nil
+}
+
+type ErrorResponse struct {
+	Error *ErrorDetail `protobuf:"bytes,1,opt,name=error" json:"error,omitempty"`
+}
+
+func (m *ErrorResponse) Reset()                    { *m = ErrorResponse{} }
+func (m *ErrorResponse) String() string            { return proto.CompactTextString(m) }
+func (*ErrorResponse) ProtoMessage()               {}
+func (*ErrorResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+func (m *ErrorResponse) GetError() *ErrorDetail {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*ID)(nil), "rpc.ID")
 	proto.RegisterType((*Nothing)(nil), "rpc.Nothing")
 	proto.RegisterType((*Query)(nil), "rpc.Query")
 	proto.RegisterType((*SearchResult)(nil), "rpc.SearchResult")
+	proto.RegisterType((*ErrorDetail)(nil), "rpc.ErrorDetail")
 	proto.RegisterType((*YouTubeResult)(nil), "rpc.YouTubeResult")
 	proto.RegisterType((*YouTubeSnippet)(nil), "rpc.YouTubeSnippet")
 	proto.RegisterType((*Thumbnail)(nil), "rpc.thumbnail")
 	proto.RegisterType((*YouTubeID)(nil), "rpc.YouTubeID")
 	proto.RegisterType((*SearchResults)(nil), "rpc.SearchResults")
+	proto.RegisterType((*TrendingQuery)(nil), "rpc.TrendingQuery")
+	proto.RegisterType((*TrendingResults)(nil), "rpc.TrendingResults")
+	proto.RegisterType((*IDsRequest)(nil), "rpc.IDsRequest")
+	proto.RegisterType((*IDs)(nil), "rpc.IDs")
+	proto.RegisterType((*IDRequest)(nil), "rpc.IDRequest")
+	proto.RegisterType((*DetailRequest)(nil), "rpc.DetailRequest")
+	proto.RegisterType((*ErrorResponse)(nil), "rpc.ErrorResponse")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -335,7 +537,8 @@ const _ = grpc.SupportPackageIsVersion4
 // Client API for GenID service
 
 type GenIDClient interface {
-	NewID(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (*ID, error)
+	NewID(ctx context.Context, in *IDRequest, opts ...grpc.CallOption) (*ID, error)
+	NewIDs(ctx context.Context, in *IDsRequest, opts ...grpc.CallOption) (*IDs, error)
 }
 
 type genIDClient struct {
@@ -346,7 +549,7 @@ func NewGenIDClient(cc *grpc.ClientConn) GenIDClient {
 	return &genIDClient{cc}
 }
 
-func (c *genIDClient) NewID(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (*ID, error) {
+func (c *genIDClient) NewID(ctx context.Context, in *IDRequest, opts ...grpc.CallOption) (*ID, error) {
 	out := new(ID)
 	err := grpc.Invoke(ctx, "/rpc.GenID/NewID", in, out, c.cc, opts...)
 	if err != nil {
@@ -355,10 +558,20 @@ func (c *genIDClient) NewID(ctx context.Context, in *Nothing, opts ...grpc.CallO
 	return out, nil
 }
 
+func (c *genIDClient) NewIDs(ctx context.Context, in *IDsRequest, opts ...grpc.CallOption) (*IDs, error) {
+	out := new(IDs)
+	err := grpc.Invoke(ctx, "/rpc.GenID/NewIDs", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for GenID service
 
 type GenIDServer interface {
-	NewID(context.Context, *Nothing) (*ID, error)
+	NewID(context.Context, *IDRequest) (*ID, error)
+	NewIDs(context.Context, *IDsRequest) (*IDs, error)
 }
 
 func RegisterGenIDServer(s *grpc.Server, srv GenIDServer) {
@@ -366,7 +579,7 @@ func RegisterGenIDServer(s *grpc.Server, srv GenIDServer) {
 }
 
 func _GenID_NewID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Nothing)
+	in := new(IDRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
@@ -378,7 +591,25 @@ func _GenID_NewID_Handler(srv interface{}, ctx context.Context, dec func(interfa
 		FullMethod: "/rpc.GenID/NewID",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(GenIDServer).NewID(ctx, req.(*Nothing))
+		return srv.(GenIDServer).NewID(ctx, req.(*IDRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GenID_NewIDs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IDsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GenIDServer).NewIDs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.GenID/NewIDs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GenIDServer).NewIDs(ctx, req.(*IDsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -391,6 +622,10 @@ var _GenID_serviceDesc = grpc.ServiceDesc{
 			MethodName: "NewID",
 			Handler:    _GenID_NewID_Handler,
 		},
+		{
+			MethodName: "NewIDs",
+			Handler:    _GenID_NewIDs_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "defs.proto",
@@ -400,6 +635,7 @@ var _GenID_serviceDesc = grpc.ServiceDesc{
 
 type SearchClient interface {
 	SearchIt(ctx context.Context, in *Query, opts ...grpc.CallOption) (*SearchResults, error)
+	Trending(ctx context.Context, in *TrendingQuery, opts ...grpc.CallOption) (*TrendingResults, error)
 }
 
 type searchClient struct {
@@ -419,10 +655,20 @@ func (c *searchClient) SearchIt(ctx context.Context, in *Query, opts ...grpc.Cal
 	return out, nil
 }
 
+func (c *searchClient) Trending(ctx context.Context, in *TrendingQuery, opts ...grpc.CallOption) (*TrendingResults, error) {
+	out := new(TrendingResults)
+	err := grpc.Invoke(ctx, "/rpc.Search/Trending", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Search service
 
 type SearchServer interface {
 	SearchIt(context.Context, *Query) (*SearchResults, error)
+	Trending(context.Context, *TrendingQuery) (*TrendingResults, error)
 }
 
 func RegisterSearchServer(s *grpc.Server, srv SearchServer) {
@@ -447,6 +693,24 @@ func _Search_SearchIt_Handler(srv interface{}, ctx context.Context, dec func(int
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Search_Trending_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TrendingQuery)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServer).Trending(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.Search/Trending",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServer).Trending(ctx, req.(*TrendingQuery))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Search_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "rpc.Search",
 	HandlerType: (*SearchServer)(nil),
@@ -455,6 +719,74 @@ var _Search_serviceDesc = grpc.ServiceDesc{
 			MethodName: "SearchIt",
 			Handler:    _Search_SearchIt_Handler,
 		},
+		{
+			MethodName: "Trending",
+			Handler:    _Search_Trending_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "defs.proto",
+}
+
+// Client API for Detailer service
+
+type DetailerClient interface {
+	Detail(ctx context.Context, in *DetailRequest, opts ...grpc.CallOption) (*Nothing, error)
+}
+
+type detailerClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewDetailerClient(cc *grpc.ClientConn) DetailerClient {
+	return &detailerClient{cc}
+}
+
+func (c *detailerClient) Detail(ctx context.Context, in *DetailRequest, opts ...grpc.CallOption) (*Nothing, error) {
+	out := new(Nothing)
+	err := grpc.Invoke(ctx, "/rpc.Detailer/Detail", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for Detailer service
+
+type DetailerServer interface {
+	Detail(context.Context, *DetailRequest) (*Nothing, error)
+}
+
+func RegisterDetailerServer(s *grpc.Server, srv DetailerServer) {
+	s.RegisterService(&_Detailer_serviceDesc, srv)
+}
+
+func _Detailer_Detail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DetailRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DetailerServer).Detail(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.Detailer/Detail",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DetailerServer).Detail(ctx, req.(*DetailRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Detailer_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "rpc.Detailer",
+	HandlerType: (*DetailerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Detail",
+			Handler:    _Detailer_Detail_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "defs.proto",
@@ -463,41 +795,70 @@ var _Search_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("defs.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 570 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x54, 0xdd, 0x6e, 0xd3, 0x30,
-	0x14, 0x5e, 0x92, 0xa5, 0x5d, 0x4e, 0xb7, 0xc1, 0x0e, 0x08, 0x45, 0x15, 0x1a, 0x95, 0xe1, 0xa2,
-	0x88, 0xd1, 0x8b, 0x22, 0x21, 0x84, 0xb8, 0x41, 0x14, 0xa1, 0x08, 0x31, 0x0d, 0x6f, 0x37, 0xbb,
-	0x23, 0xad, 0x4d, 0x63, 0x2d, 0x4d, 0x22, 0xdb, 0xd9, 0xd6, 0x27, 0xe2, 0x35, 0x78, 0x34, 0x64,
-	0xc7, 0x09, 0x19, 0xe3, 0xee, 0x7c, 0x9f, 0xbf, 0xf3, 0x7f, 0x12, 0x00, 0xc6, 0x7f, 0xaa, 0x59,
-	0x25, 0x4b, 0x5d, 0x62, 0x20, 0xab, 0x15, 0x19, 0x83, 0x9f, 0x2c, 0xf0, 0x31, 0x84, 0xd7, 0x69,
-	0x5e, 0xf3, 0xd8, 0x9b, 0x78, 0xd3, 0x88, 0x36, 0x80, 0x44, 0x30, 0x3c, 0x2d, 0x75, 0x26, 0x8a,
-	0x35, 0xd9, 0x40, 0xf8, 0xbd, 0xe6, 0x72, 0x8b, 0x63, 0xd8, 0xbb, 0xe2, 0xdb, 0x9b, 0x52, 0x32,
-	0xe5, 0xc4, 0x1d, 0x36, 0x6f, 0x9b, 0xf4, 0xf6, 0x2c, 0x5d, 0x73, 0x15, 0xfb, 0x13, 0x6f, 0x1a,
-	0xd2, 0x0e, 0xe3, 0x09, 0x1c, 0x6d, 0xd2, 0x5b, 0xca, 0x55, 0x9d, 0x6b, 0x75, 0xc6, 0xa5, 0x61,
-	0xe3, 0xc0, 0x8a, 0xee, 0x3f, 0x90, 0x1f, 0xb0, 0x7f, 0xce, 0x53, 0xb9, 0xca, 0x1a, 0xde, 0xd4,
-	0x27, 0x0a, 0xc6, 0x6f, 0x6d, 0xca, 0x5d, 0xda, 0x00, 0x9c, 0x42, 0x28, 0x34, 0xdf, 0x98, 0x64,
-	0xc1, 0x74, 0x34, 0xc7, 0x99, 0xac, 0x56, 0xb3, 0xcb, 0xb2, 0xbe, 0xa8, 0x97, 0xbc, 0x71, 0xa4,
-	0x8d, 0x00, 0x1f, 0x42, 0xc0, 0xa5, 0xb4, 0xf9, 0x22, 0x6a, 0x4c, 0xf2, 0xcb, 0x83, 0x83, 0x3b,
-	0x52, 0x7c, 0x06, 0x03, 0x23, 0x4e, 0x98, 0x4d, 0x32, 0x9a, 0x0f, 0x6d, 0xb8, 0x64, 0x41, 0x1d,
-	0x8d, 0x08, 0xbb, 0x5c, 0xa7, 0x6b, 0xdb, 0x5a, 0x44, 0xad, 0x8d, 0xc7, 0xe0, 0x0b, 0x66, 0xe3,
-	0x8e, 0xe6, 0x87, 0xfd, 0xfc, 0xc9, 0x82, 0xfa, 0xc2, 0xfa, 0x5c, 0x89, 0x82, 0xc5, 0xbb, 0x8d,
-	0x8f, 0xb1, 0xf1, 0x35, 0x0c, 0x55, 0x21, 0xaa, 0x8a, 0xeb, 0x38, 0xb4, 0x8e, 0x8f, 0xfa, 0x8e,
-	0xe7, 0xcd, 0x13, 0x6d, 0x35, 0xe4, 0xb7, 0x0f, 0x87, 0x77, 0xdf, 0xf0, 0x29, 0x44, 0xab, 0x2c,
-	0x2d, 0x0a, 0x9e, 0xbb, 0x6a, 0x23, 0xfa, 0x97, 0x40, 0x02, 0xfb, 0x0e, 0x5c, 0x08, 0x9d, 0x73,
-	0x57, 0xef, 0x1d, 0x0e, 0x27, 0x30, 0x62, 0x5c, 0xad, 0xa4, 0xa8, 0xb4, 0x28, 0x0b, 0x37, 0x98,
-	0x3e, 0x65, 0x14, 0x55, 0xbd, 0xcc, 0x85, 0xca, 0x38, 0xfb, 0xa8, 0x5d, 0x03, 0x7d, 0x0a, 0x3f,
-	0x01, 0xe8, 0xac, 0xde, 0x2c, 0x8b, 0x54, 0xe4, 0x2a, 0x0e, 0xed, 0x0e, 0x9e, 0xff, 0xa7, 0x95,
-	0xd9, 0x45, 0xa7, 0xfa, 0x5c, 0x68, 0xb9, 0xa5, 0x3d, 0x37, 0xb3, 0x59, 0x6d, 0xab, 0x1c, 0x34,
-	0x97, 0x67, 0xc1, 0xf8, 0x1b, 0x3c, 0xf8, 0xc7, 0xc9, 0xac, 0xf0, 0x8a, 0x6f, 0x5d, 0xb7, 0xc6,
-	0xc4, 0x17, 0xed, 0xd1, 0xfa, 0xbd, 0xf1, 0x77, 0xa1, 0xdd, 0x11, 0xbf, 0xf7, 0xdf, 0x79, 0xe4,
-	0x2b, 0x44, 0x1d, 0x8f, 0x4f, 0x60, 0x90, 0x71, 0xb1, 0xce, 0xb4, 0x8d, 0x15, 0x50, 0x87, 0x4c,
-	0x25, 0x37, 0x82, 0xe9, 0xcc, 0x86, 0x0b, 0x68, 0x03, 0x4c, 0xda, 0x5a, 0xe6, 0xed, 0xe5, 0xd4,
-	0x32, 0x27, 0x97, 0x10, 0x75, 0x3b, 0xee, 0xf6, 0xeb, 0xf5, 0xf6, 0x1b, 0xc3, 0xf0, 0x5a, 0x30,
-	0x5e, 0x26, 0xcc, 0x8d, 0xbe, 0x85, 0x78, 0x0c, 0x50, 0xe5, 0xe9, 0x36, 0x17, 0x4a, 0x27, 0xcc,
-	0xc5, 0xec, 0x31, 0xe4, 0x03, 0x1c, 0xf4, 0xcf, 0x5e, 0xe1, 0x2b, 0x18, 0x3a, 0x33, 0xf6, 0xec,
-	0x7c, 0x8f, 0x6c, 0x93, 0x7d, 0x11, 0x6d, 0x15, 0xf3, 0x97, 0x10, 0x7e, 0xe1, 0x45, 0xb2, 0xc0,
-	0x09, 0x84, 0xa7, 0xfc, 0x26, 0x59, 0xe0, 0xbe, 0x55, 0xbb, 0x6f, 0x78, 0xdc, 0x1e, 0x34, 0xd9,
-	0x99, 0xbf, 0x85, 0x41, 0x13, 0x03, 0x4f, 0x60, 0xaf, 0xb1, 0x12, 0x8d, 0x60, 0x05, 0xf6, 0x3b,
-	0x1f, 0xe3, 0xbd, 0x44, 0x8a, 0xec, 0x2c, 0x07, 0xf6, 0xcf, 0xf1, 0xe6, 0x4f, 0x00, 0x00, 0x00,
-	0xff, 0xff, 0xe9, 0x54, 0x6d, 0x23, 0x47, 0x04, 0x00, 0x00,
+	// 1031 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x55, 0x4f, 0x8f, 0xdb, 0x44,
+	0x14, 0x97, 0x93, 0x75, 0xb2, 0x7e, 0xc9, 0xb6, 0xbb, 0xc3, 0x6a, 0x1b, 0x59, 0xed, 0x62, 0xa6,
+	0x08, 0xad, 0xca, 0x26, 0x51, 0xc3, 0xa1, 0x28, 0x20, 0xa4, 0xd2, 0x54, 0x95, 0x41, 0x54, 0xad,
+	0xbb, 0x97, 0x22, 0x2e, 0x5e, 0x7b, 0xd6, 0x1e, 0xd6, 0xf1, 0x98, 0x19, 0xbb, 0xbb, 0x11, 0x37,
+	0x4e, 0x9c, 0xe1, 0xca, 0x9d, 0x2f, 0xc0, 0x81, 0x23, 0xdf, 0x81, 0xaf, 0xc0, 0x07, 0x41, 0xf3,
+	0xec, 0x49, 0x1c, 0xb6, 0x82, 0x03, 0x27, 0xbf, 0xf7, 0x7b, 0xbf, 0xf7, 0x67, 0xe6, 0x3d, 0xbf,
+	0x01, 0x88, 0xd9, 0x85, 0x9a, 0x14, 0x52, 0x94, 0x82, 0x74, 0x65, 0x11, 0xb9, 0x77, 0x13, 0x21,
+	0x92, 0x8c, 0x4d, 0xc3, 0x82, 0x4f, 0xc3, 0x3c, 0x17, 0x65, 0x58, 0x72, 0x91, 0x37, 0x14, 0xf7,
+	0x14, 0x3f, 0xd1, 0x38, 0x61, 0xf9, 0x58, 0x5d, 0x85, 0x49, 0xc2, 0xe4, 0x54, 0x14, 0xc8, 0xb8,
+	0xc9, 0xa6, 0x2e, 0x74, 0xfc, 0x05, 0x39, 0x04, 0xfb, 0x4d, 0x98, 0x55, 0x6c, 0x64, 0x79, 0xd6,
+	0x89, 0x13, 0xd4, 0x0a, 0x75, 0xa0, 0xff, 0x5c, 0x94, 0x29, 0xcf, 0x13, 0xba, 0x04, 0xfb, 0x65,
+	0xc5, 0xe4, 0x8a, 0xb8, 0xb0, 0x7b, 0xc9, 0x56, 0x57, 0x42, 0xc6, 0xaa, 0x21, 0xaf, 0x75, 0x6d,
+	0x5b, 0x86, 0xd7, 0x2f, 0xc2, 0x84, 0xa9, 0x51, 0xc7, 0xb3, 0x4e, 0xec, 0x60, 0xad, 0x93, 0x53,
+	0x38, 0x58, 0x86, 0xd7, 0x01, 0x53, 0x55, 0x56, 0xaa, 0x17, 0x4c, 0x6a, 0x74, 0xd4, 0x45, 0xd2,
+	0x4d, 0x03, 0xfd, 0xd1, 0x82, 0xe1, 0x2b, 0x16, 0xca, 0x28, 0xad, 0x0d, 0xba, 0x40, 0x9e, 0xc7,
+	0xec, 0x1a, 0x73, 0xee, 0x04, 0xb5, 0x42, 0x4e, 0xc0, 0xe6, 0x25, 0x5b, 0xea, 0x6c, 0xdd, 0x93,
+	0xc1, 0x8c, 0x4c, 0x64, 0x11, 0x4d, 0x5e, 0x8b, 0xea, 0xac, 0x3a, 0x67, 0xb5, 0x63, 0x50, 0x13,
+	0xc8, 0x3e, 0x74, 0x99, 0x94, 0x98, 0xd0, 0x09, 0xb4, 0x48, 0x3e, 0x00, 0x9b, 0x49, 0x29, 0xe4,
+	0x68, 0xc7, 0xb3, 0x4e, 0x06, 0xb3, 0x7d, 0xf4, 0x7d, 0xaa, 0x91, 0x05, 0x2b, 0x43, 0x9e, 0x05,
+	0xb5, 0x99, 0x7e, 0x02, 0x83, 0x16, 0x4a, 0x08, 0xec, 0x44, 0x22, 0x36, 0x17, 0x85, 0x32, 0x19,
+	0x41, 0x7f, 0xc9, 0x94, 0xd2, 0x27, 0xea, 0x20, 0x6c, 0x54, 0xfa, 0xab, 0x05, 0x7b, 0x5b, 0xf5,
+	0x90, 0x77, 0xa1, 0xa7, 0x2b, 0xf2, 0x63, 0x8c, 0x30, 0x98, 0xf5, 0x31, 0xaf, 0xbf, 0x08, 0x1a,
+	0x58, 0x27, 0x60, 0x65, 0x98, 0x34, 0x91, 0x50, 0x26, 0xc7, 0xd0, 0xe1, 0x31, 0x16, 0x3f, 0x98,
+	0xdd, 0x6a, 0x1f, 0xd2, 0x5f, 0x04, 0x1d, 0x8e, 0x3e, 0x97, 0x3c, 0x8f, 0xf1, 0x28, 0x4e, 0x80,
+	0x32, 0x19, 0x43, 0x5f, 0xe5, 0xbc, 0x28, 0x58, 0x39, 0xb2, 0xd1, 0xf1, 0x9d, 0xb6, 0xe3, 0xab,
+	0xda, 0x14, 0x18, 0x0e, 0xfd, 0xa3, 0x03, 0xb7, 0xb6, 0x6d, 0xe4, 0x2e, 0x38, 0x51, 0x1a, 0xe6,
+	0x39, 0xcb, 0x9a, 0x6a, 0x9d, 0x60, 0x03, 0x10, 0x0a, 0xc3, 0x46, 0x39, 0xe3, 0x65, 0x66, 0x4e,
+	0xbe, 0x85, 0x11, 0x0f, 0x06, 0x31, 0x53, 0x91, 0xe4, 0x38, 0x7e, 0xcd, 0xed, 0xb7, 0x21, 0xcd,
+	0x28, 0xaa, 0xf3, 0x8c, 0xab, 0x94, 0xc5, 0x8f, 0xcb, 0xe6, 0x00, 0x6d, 0x88, 0x3c, 0x01, 0x28,
+	0xd3, 0x6a, 0x79, 0x9e, 0x87, 0x3c, 0x53, 0x23, 0x1b, 0x1b, 0x7d, 0xff, 0x2d, 0x47, 0x99, 0x9c,
+	0xad, 0x59, 0x4f, 0xf3, 0x52, 0xae, 0x82, 0x96, 0x9b, 0x1e, 0x9f, 0x12, 0xab, 0xec, 0xd5, 0xf3,
+	0x8d, 0x8a, 0xfb, 0x15, 0xdc, 0xfe, 0x87, 0x93, 0x9e, 0x93, 0x4b, 0xb6, 0x6a, 0x4e, 0xab, 0x45,
+	0xf2, 0xbe, 0xf9, 0x35, 0x3a, 0xad, 0xeb, 0x5f, 0x87, 0x6e, 0x7e, 0x95, 0x79, 0xe7, 0x63, 0x8b,
+	0x7e, 0x09, 0xce, 0x1a, 0x27, 0x47, 0xd0, 0x4b, 0x19, 0x4f, 0xd2, 0x12, 0x63, 0x75, 0x83, 0x46,
+	0xd3, 0x95, 0x5c, 0xf1, 0xb8, 0x4c, 0x31, 0x5c, 0x37, 0xa8, 0x15, 0x9d, 0xb6, 0x92, 0x99, 0x19,
+	0xcf, 0x4a, 0x66, 0xf4, 0x35, 0x38, 0xeb, 0x1e, 0xaf, 0xfb, 0x6b, 0xb5, 0xfa, 0x3b, 0x82, 0xfe,
+	0x1b, 0x1e, 0x33, 0xe1, 0xc7, 0x66, 0xe8, 0x1a, 0x95, 0x1c, 0x03, 0x14, 0x59, 0xb8, 0xca, 0xb8,
+	0x2a, 0xfd, 0xb8, 0x89, 0xd9, 0x42, 0xe8, 0xa7, 0xb0, 0xd7, 0xfe, 0xb7, 0x14, 0xf9, 0x10, 0xfa,
+	0x8d, 0x38, 0xb2, 0xf0, 0x7e, 0x0f, 0xf0, 0x90, 0x6d, 0x52, 0x60, 0x18, 0x54, 0xc0, 0xde, 0x99,
+	0x64, 0x79, 0xcc, 0xf3, 0xa4, 0xde, 0x08, 0xc7, 0x00, 0x92, 0x25, 0x5c, 0xe4, 0x4f, 0x36, 0xff,
+	0x45, 0x0b, 0xd1, 0xf6, 0x28, 0x2c, 0x59, 0x22, 0xe4, 0x6a, 0x5d, 0x6b, 0x0b, 0xd1, 0xf6, 0xcd,
+	0x02, 0x68, 0x56, 0x42, 0x0b, 0xa1, 0xdf, 0xc3, 0x6d, 0x93, 0xd0, 0x14, 0xfc, 0x7f, 0x53, 0xae,
+	0xf7, 0x46, 0xf7, 0x3f, 0xf6, 0x06, 0x9d, 0x03, 0xf8, 0x0b, 0x15, 0xb0, 0xef, 0x2a, 0xa6, 0xb0,
+	0x79, 0x91, 0xa8, 0xf2, 0xba, 0xa7, 0x76, 0x50, 0x2b, 0xba, 0xd5, 0x2a, 0x4a, 0xd9, 0xd2, 0xfc,
+	0x03, 0x8d, 0x46, 0xef, 0x41, 0xd7, 0x5f, 0x28, 0x6d, 0xc6, 0x19, 0xa9, 0x2f, 0xd7, 0x09, 0x1a,
+	0x8d, 0xde, 0x07, 0xc7, 0x5f, 0x98, 0xc8, 0x9b, 0x18, 0xd6, 0x56, 0x8c, 0xf7, 0x60, 0xaf, 0x59,
+	0x47, 0x0d, 0x71, 0x1f, 0xba, 0x3c, 0x36, 0xa1, 0xb4, 0x48, 0x1f, 0xc1, 0x1e, 0x2e, 0xa8, 0x80,
+	0xa9, 0x42, 0xe4, 0x8a, 0x6d, 0x36, 0x9b, 0xf5, 0xaf, 0x9b, 0x6d, 0xc6, 0xc1, 0x7e, 0xc6, 0x72,
+	0x7f, 0x41, 0xc6, 0x60, 0x3f, 0x67, 0x57, 0xfe, 0x82, 0xdc, 0x32, 0xcb, 0xa8, 0x4e, 0xe6, 0x9a,
+	0xe5, 0x44, 0x07, 0x3f, 0xfc, 0xf9, 0xd7, 0xcf, 0x1d, 0x9b, 0x74, 0xa7, 0x3c, 0x26, 0x0f, 0xa1,
+	0x87, 0x74, 0x45, 0x6e, 0x37, 0x76, 0x73, 0x41, 0xee, 0xae, 0x01, 0xe8, 0x10, 0x3d, 0x7a, 0x64,
+	0x67, 0xca, 0x63, 0x35, 0xfb, 0xc5, 0x82, 0x5e, 0x3d, 0x4e, 0xc4, 0x87, 0xdd, 0x5a, 0xf2, 0x4b,
+	0x02, 0x48, 0xc7, 0x31, 0x72, 0xc9, 0x8d, 0x99, 0x53, 0xf4, 0x1e, 0x06, 0xb9, 0x33, 0xb7, 0x1e,
+	0x7c, 0xed, 0x90, 0xfe, 0x54, 0xa1, 0x91, 0x1a, 0x81, 0x3c, 0x83, 0x5d, 0x33, 0x19, 0xa4, 0x76,
+	0xdf, 0x9a, 0x4c, 0xf7, 0x70, 0x0b, 0x33, 0x41, 0x0f, 0x30, 0xe8, 0x80, 0x38, 0xd3, 0xb2, 0xb1,
+	0xcc, 0xbe, 0x80, 0xdd, 0xfa, 0x6a, 0x98, 0x24, 0x9f, 0x41, 0xcf, 0xac, 0x7a, 0x74, 0xdf, 0xba,
+	0x7e, 0x77, 0x88, 0x98, 0x79, 0x15, 0x09, 0x86, 0x1a, 0xce, 0xad, 0x07, 0xb4, 0x3f, 0x8d, 0x91,
+	0xf8, 0xf9, 0xef, 0xd6, 0x4f, 0x8f, 0x7f, 0xb3, 0xc8, 0x01, 0x0c, 0x97, 0x2c, 0xe6, 0xe1, 0xb8,
+	0xae, 0x75, 0x66, 0x3d, 0x9c, 0xef, 0x87, 0x45, 0x91, 0xf1, 0x08, 0x9f, 0xdf, 0xe9, 0xb7, 0x4a,
+	0xe4, 0xf3, 0xa3, 0x36, 0x72, 0x3d, 0xc6, 0x17, 0xf9, 0xbc, 0xba, 0x98, 0xdf, 0x79, 0x0b, 0xae,
+	0x1d, 0x82, 0x6f, 0xa0, 0x1f, 0xb3, 0x8b, 0x50, 0x3f, 0x24, 0x2f, 0xe1, 0xd1, 0x59, 0xca, 0x3c,
+	0x6c, 0xe5, 0xa9, 0x77, 0x95, 0x0a, 0xc5, 0x3c, 0xfd, 0x1a, 0x79, 0x5c, 0x79, 0x22, 0x67, 0x9e,
+	0xb8, 0xf0, 0x4a, 0x04, 0xc5, 0x85, 0x27, 0x8b, 0x68, 0x8a, 0x44, 0x35, 0x49, 0xc4, 0x84, 0x1c,
+	0xc1, 0xa1, 0x4b, 0x36, 0x53, 0x61, 0x06, 0xe7, 0xbc, 0x87, 0x89, 0x3e, 0xfa, 0x7b, 0x00, 0x2e,
+	0x20, 0xe9, 0x98, 0x71, 0x08, 0x00, 0x00,
 }
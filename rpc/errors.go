@@ -0,0 +1,208 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/orijtech/media-search/resilience"
+)
+
+// Code classifies a failure so that clients can branch on it
+// regardless of whether they came in over gRPC or HTTP.
+type Code string
+
+const (
+	CodeInvalidQuery        Code = "INVALID_QUERY"
+	CodeMethodNotAllowed    Code = "METHOD_NOT_ALLOWED"
+	CodeQuotaExceeded       Code = "QUOTA_EXCEEDED"
+	CodeUpstreamUnavailable Code = "UPSTREAM_UNAVAILABLE"
+	CodeNotFound            Code = "NOT_FOUND"
+	CodeRateLimited         Code = "RATE_LIMITED"
+	CodeNotAcceptable       Code = "NOT_ACCEPTABLE"
+	CodeUnauthenticated     Code = "UNAUTHENTICATED"
+	CodeInternal            Code = "INTERNAL"
+)
+
+// errorDomain is the ErrorInfo domain under which codes travel in gRPC statuses.
+const errorDomain = "media-search"
+
+type codeMapping struct {
+	grpc codes.Code
+	http int
+}
+
+var codeMappings = map[Code]codeMapping{
+	CodeInvalidQuery:        {codes.InvalidArgument, http.StatusBadRequest},
+	CodeMethodNotAllowed:    {codes.Unimplemented, http.StatusMethodNotAllowed},
+	CodeQuotaExceeded:       {codes.ResourceExhausted, http.StatusServiceUnavailable},
+	CodeUpstreamUnavailable: {codes.Unavailable, http.StatusBadGateway},
+	CodeNotFound:            {codes.NotFound, http.StatusNotFound},
+	CodeRateLimited:         {codes.ResourceExhausted, http.StatusTooManyRequests},
+	CodeNotAcceptable:       {codes.InvalidArgument, http.StatusNotAcceptable},
+	CodeUnauthenticated:     {codes.Unauthenticated, http.StatusUnauthorized},
+	CodeInternal:            {codes.Internal, http.StatusInternalServerError},
+}
+
+// Error is the error type shared by all the services.
+type Error struct {
+	Code    Code   `json:"code"`
+	Message string `json:"message"`
+}
+
+var _ error = (*Error)(nil)
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func Errorf(code Code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// GRPCStatus allows the gRPC server to transmit e as a status
+// whose ErrorInfo reason carries e.Code, see FromError.
+func (e *Error) GRPCStatus() *status.Status {
+	st := status.New(e.mapping().grpc, e.Message)
+	if withDetails, err := st.WithDetails(&errdetails.ErrorInfo{Reason: string(e.Code), Domain: errorDomain}); err == nil {
+		st = withDetails
+	}
+	return st
+}
+
+// HTTPStatus returns the HTTP status code that e maps to.
+func (e *Error) HTTPStatus() int {
+	return e.mapping().http
+}
+
+func (e *Error) mapping() codeMapping {
+	if m, ok := codeMappings[e.Code]; ok {
+		return m
+	}
+	return codeMappings[CodeInternal]
+}
+
+// Detail returns the protobuf representation of e
+// for embedding in messages such as SearchResult.
+func (e *Error) Detail() *ErrorDetail {
+	return &ErrorDetail{Code: string(e.Code), Message: e.Message}
+}
+
+// FromError converts any error into an *Error, recovering the code from
+// gRPC statuses and classifying errors returned by the YouTube API.
+func FromError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	if st, ok := status.FromError(err); ok {
+		return fromStatus(st)
+	}
+	return classifyUpstreamError(err)
+}
+
+func fromStatus(st *status.Status) *Error {
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Domain == errorDomain {
+			return &Error{Code: Code(info.Reason), Message: st.Message()}
+		}
+	}
+
+	// Otherwise the status didn't come from one of our services.
+	code := CodeInternal
+	switch st.Code() {
+	case codes.InvalidArgument:
+		code = CodeInvalidQuery
+	case codes.Unimplemented:
+		code = CodeMethodNotAllowed
+	case codes.ResourceExhausted:
+		code = CodeRateLimited
+	case codes.Unavailable, codes.DeadlineExceeded:
+		code = CodeUpstreamUnavailable
+	case codes.NotFound:
+		code = CodeNotFound
+	case codes.Unauthenticated:
+		code = CodeUnauthenticated
+	}
+	return &Error{Code: code, Message: st.Message()}
+}
+
+// classifyUpstreamError maps errors from the YouTube API and the network to codes.
+func classifyUpstreamError(err error) *Error {
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) {
+		for _, item := range gerr.Errors {
+			switch item.Reason {
+			case "quotaExceeded", "dailyLimitExceeded":
+				return &Error{Code: CodeQuotaExceeded, Message: gerr.Message}
+			case "rateLimitExceeded", "userRateLimitExceeded":
+				return &Error{Code: CodeRateLimited, Message: gerr.Message}
+			}
+		}
+		switch {
+		case gerr.Code == http.StatusBadRequest:
+			return &Error{Code: CodeInvalidQuery, Message: gerr.Message}
+		case gerr.Code == http.StatusNotFound:
+			return &Error{Code: CodeNotFound, Message: gerr.Message}
+		case gerr.Code == http.StatusTooManyRequests:
+			return &Error{Code: CodeRateLimited, Message: gerr.Message}
+		case gerr.Code >= 500:
+			return &Error{Code: CodeUpstreamUnavailable, Message: gerr.Message}
+		}
+	}
+
+	var nerr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, resilience.ErrBreakerOpen) || errors.As(err, &nerr) {
+		return &Error{Code: CodeUpstreamUnavailable, Message: err.Error()}
+	}
+	return &Error{Code: CodeInternal, Message: err.Error()}
+}
+
+// IsTransient reports whether err is a failure that might not recur if retried later.
+func IsTransient(err error) bool {
+	switch FromError(err).Code {
+	case CodeUpstreamUnavailable, CodeRateLimited:
+		return true
+	default:
+		return false
+	}
+}
+
+// WriteHTTPError writes err as a JSON body of the form
+//
+//	{"error": {"code": "INVALID_QUERY", "message": "..."}}
+//
+// with the HTTP status code that its Code maps to.
+func WriteHTTPError(w http.ResponseWriter, err error) {
+	e := FromError(err)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(e.HTTPStatus())
+	enc := json.NewEncoder(w)
+	_ = enc.Encode(map[string]*Error{"error": e})
+}
@@ -0,0 +1,410 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpc
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+)
+
+// The formats that search results can be written in, see NegotiateFormat.
+const (
+	// FormatJSON is the JSON encoding of the Go structs, the default.
+	FormatJSON = "json"
+	// FormatProto is the protobuf binary encoding of SearchResults.
+	FormatProto = "proto"
+	// FormatProtoJSON is the canonical protobuf JSON mapping of SearchResults,
+	// with the field names of defs.proto and 64 bit integers as strings.
+	FormatProtoJSON = "protojson"
+	// FormatCSV is a CSV table of the items found, one per row.
+	FormatCSV = "csv"
+	// FormatAtom and FormatRSS are feeds of the items found.
+	FormatAtom = "atom"
+	FormatRSS  = "rss"
+)
+
+// mediaTypes are the media types that can be asked for in an Accept
+// header, in the order of preference of the server, and their formats.
+var mediaTypes = []struct {
+	mediaType string
+	format    string
+}{
+	{"application/json", FormatJSON},
+	{"application/x-protobuf", FormatProto},
+	{"application/protobuf", FormatProto},
+	{"application/vnd.google.protobuf", FormatProto},
+	{"application/x-protojson", FormatProtoJSON},
+	{"text/csv", FormatCSV},
+	{"application/atom+xml", FormatAtom},
+	{"application/rss+xml", FormatRSS},
+}
+
+// contentTypes are the Content-Type headers of the formats.
+var contentTypes = map[string]string{
+	FormatJSON:      "application/json",
+	FormatProto:     "application/x-protobuf",
+	FormatProtoJSON: "application/json",
+	FormatCSV:       "text/csv; charset=utf-8; header=present",
+	FormatAtom:      "application/atom+xml; charset=utf-8",
+	FormatRSS:       "application/rss+xml; charset=utf-8",
+}
+
+// NegotiateFormat returns the format that the search results to r are to
+// be written in: that of its "format" parameter if set, otherwise the one
+// its Accept header prefers, FormatJSON if it has none. It returns
+// CodeInvalidQuery if the parameter is unknown, CodeNotAcceptable if
+// none of the formats is acceptable.
+func NegotiateFormat(r *http.Request) (string, error) {
+	return negotiateFormat(r, FormatJSON, FormatProto, FormatProtoJSON, FormatCSV, FormatAtom, FormatRSS)
+}
+
+// negotiateFormat is NegotiateFormat, out of formats only.
+func negotiateFormat(r *http.Request, formats ...string) (string, error) {
+	offered := func(format string) bool {
+		for _, f := range formats {
+			if f == format {
+				return true
+			}
+		}
+		return false
+	}
+	if format := r.URL.Query().Get("format"); format != "" {
+		if !offered(format) {
+			return "", Errorf(CodeInvalidQuery, "Unknown format %q, expecting one of %s", format, strings.Join(formats, ", "))
+		}
+		return format, nil
+	}
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return FormatJSON, nil
+	}
+	ranges := parseAccept(accept)
+	format, bestQ := "", 0.0
+	for _, mt := range mediaTypes {
+		if !offered(mt.format) {
+			continue
+		}
+		if q := acceptQuality(ranges, mt.mediaType); q > bestQ {
+			format, bestQ = mt.format, q
+		}
+	}
+	if format == "" {
+		return "", Errorf(CodeNotAcceptable, "None of the media types accepted %q is available", accept)
+	}
+	return format, nil
+}
+
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		slash := strings.IndexByte(mediaType, '/')
+		if slash < 0 {
+			// Some clients send a bare "*".
+			if mediaType != "*" {
+				continue
+			}
+			mediaType, slash = "*/*", 1
+		}
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(s, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{typ: mediaType[:slash], subtype: mediaType[slash+1:], q: q})
+	}
+	return ranges
+}
+
+// acceptQuality returns the quality of mediaType given by the most
+// specific of ranges that match it, 0 if none does.
+func acceptQuality(ranges []mediaRange, mediaType string) float64 {
+	slash := strings.IndexByte(mediaType, '/')
+	typ, subtype := mediaType[:slash], mediaType[slash+1:]
+	q, specificity := 0.0, -1
+	for _, mr := range ranges {
+		s := -1
+		switch {
+		case mr.typ == typ && mr.subtype == subtype:
+			s = 2
+		case mr.typ == typ && mr.subtype == "*":
+			s = 1
+		case mr.typ == "*" && mr.subtype == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = mr.q, s
+		}
+	}
+	return q
+}
+
+// WriteSearchResults writes results, found for q, to w in format,
+// replying with the error instead if they can't be encoded.
+func WriteSearchResults(w http.ResponseWriter, r *http.Request, format string, q *Query, results *SearchResults) {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case FormatProto:
+		var blob []byte
+		if blob, err = proto.Marshal(results); err == nil {
+			buf.Write(blob)
+		}
+	case FormatProtoJSON:
+		err = new(jsonpb.Marshaler).Marshal(&buf, results)
+	case FormatCSV:
+		err = writeCSV(&buf, results)
+	case FormatAtom:
+		err = writeAtom(&buf, r, q, results)
+	case FormatRSS:
+		err = writeRSS(&buf, r, q, results)
+	default:
+		err = json.NewEncoder(&buf).Encode(results)
+	}
+	if err != nil {
+		WriteHTTPError(w, Errorf(CodeInternal, "Encoding the results as %s: %v", format, err))
+		return
+	}
+	w.Header().Set("Content-Type", contentTypes[format])
+	w.Header().Add("Vary", "Accept")
+	_, _ = w.Write(buf.Bytes())
+}
+
+// csvHeader are the columns of the CSV tables, a row per item found
+// or, for the pages that failed, with only the page and the error.
+var csvHeader = []string{
+	"page", "kind", "video_id", "playlist_id", "channel_id", "channel_title",
+	"title", "description", "published_at", "thumbnail_url", "url", "error",
+}
+
+func writeCSV(buf *bytes.Buffer, results *SearchResults) error {
+	cw := csv.NewWriter(buf)
+	cw.Write(csvHeader)
+	for _, result := range results.GetResults() {
+		page := strconv.FormatUint(result.GetIndex(), 10)
+		if err := result.GetError(); err != nil || result.GetErr() != "" {
+			msg := result.GetErr()
+			if err != nil {
+				msg = err.GetCode() + ": " + err.GetMessage()
+			}
+			cw.Write([]string{page, "", "", "", "", "", "", "", "", "", "", msg})
+		}
+		for _, item := range result.GetItems() {
+			id, snip := item.GetId(), item.GetSnippet()
+			cw.Write([]string{
+				page, id.GetKind(), id.GetVideoId(), id.GetPlaylistId(),
+				snip.GetChannelId(), snip.GetChannelTitle(), snip.GetTitle(), snip.GetDescription(),
+				snip.GetPublishedAt(), thumbnailURL(snip), itemURL(item), "",
+			})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// thumbnailURL returns the URL of the default thumbnail of snip, if any.
+func thumbnailURL(snip *YouTubeSnippet) string {
+	for _, size := range []string{"default", "medium", "high"} {
+		if th := snip.GetThumbnails()[size]; th != nil {
+			return th.GetUrl()
+		}
+	}
+	return ""
+}
+
+// itemURL returns the URL of the video, playlist or channel found.
+func itemURL(item *YouTubeResult) string {
+	id := item.GetId()
+	switch {
+	case id.GetVideoId() != "":
+		return "https://www.youtube.com/watch?v=" + url.QueryEscape(id.GetVideoId())
+	case id.GetPlaylistId() != "":
+		return "https://www.youtube.com/playlist?list=" + url.QueryEscape(id.GetPlaylistId())
+	case item.GetSnippet().GetChannelId() != "":
+		return "https://www.youtube.com/channel/" + url.PathEscape(item.GetSnippet().GetChannelId())
+	}
+	return ""
+}
+
+// itemGUID identifies an item in the feeds the way YouTube's own feeds do.
+func itemGUID(item *YouTubeResult) string {
+	id := item.GetId()
+	switch {
+	case id.GetVideoId() != "":
+		return "yt:video:" + id.GetVideoId()
+	case id.GetPlaylistId() != "":
+		return "yt:playlist:" + id.GetPlaylistId()
+	}
+	return "yt:channel:" + item.GetSnippet().GetChannelId()
+}
+
+func feedTitle(q *Query) string {
+	return "YouTube search results for " + strconv.Quote(q.GetKeywords())
+}
+
+// requestURL returns the absolute URL of r, as the feeds link to themselves.
+func requestURL(r *http.Request) string {
+	u := *r.URL
+	u.Host = r.Host
+	u.Scheme = "http"
+	if r.TLS != nil {
+		u.Scheme = "https"
+	}
+	return u.String()
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Link      atomLink    `xml:"link"`
+	Published string      `xml:"published,omitempty"`
+	Updated   string      `xml:"updated"`
+	Author    *atomAuthor `xml:"author,omitempty"`
+	Summary   string      `xml:"summary,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+func writeAtom(buf *bytes.Buffer, r *http.Request, q *Query, results *SearchResults) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	feed := &atomFeed{
+		ID:      "urn:media-search:search:" + url.QueryEscape(q.GetKeywords()),
+		Title:   feedTitle(q),
+		Updated: now,
+		Link:    atomLink{Rel: "self", Href: requestURL(r)},
+	}
+	for _, result := range results.GetResults() {
+		for _, item := range result.GetItems() {
+			snip := item.GetSnippet()
+			entry := atomEntry{
+				ID:        itemGUID(item),
+				Title:     snip.GetTitle(),
+				Link:      atomLink{Href: itemURL(item)},
+				Published: snip.GetPublishedAt(),
+				Updated:   snip.GetPublishedAt(),
+				Summary:   snip.GetDescription(),
+				Author:    &atomAuthor{Name: snip.GetChannelTitle()},
+			}
+			// Atom requires every entry to have an updated time and,
+			// as the feed has none, an author with a name.
+			if entry.Updated == "" {
+				entry.Updated = now
+			}
+			if entry.Author.Name == "" {
+				entry.Author.Name = "YouTube"
+			}
+			if snip.GetChannelId() != "" {
+				entry.Author.URI = "https://www.youtube.com/channel/" + url.PathEscape(snip.GetChannelId())
+			}
+			feed.Entries = append(feed.Entries, entry)
+		}
+	}
+	buf.WriteString(xml.Header)
+	return xml.NewEncoder(buf).Encode(feed)
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func writeRSS(buf *bytes.Buffer, r *http.Request, q *Query, results *SearchResults) error {
+	feed := &rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         feedTitle(q),
+			Link:          requestURL(r),
+			Description:   feedTitle(q),
+			LastBuildDate: time.Now().UTC().Format(time.RFC1123Z),
+		},
+	}
+	for _, result := range results.GetResults() {
+		for _, item := range result.GetItems() {
+			snip := item.GetSnippet()
+			ri := rssItem{
+				Title:       snip.GetTitle(),
+				Link:        itemURL(item),
+				Description: snip.GetDescription(),
+				GUID:        rssGUID{Value: itemGUID(item)},
+			}
+			// RSS dates are in RFC 822 rather than the RFC 3339 of YouTube.
+			if t, err := time.Parse(time.RFC3339, snip.GetPublishedAt()); err == nil {
+				ri.PubDate = t.UTC().Format(time.RFC1123Z)
+			}
+			feed.Channel.Items = append(feed.Channel.Items, ri)
+		}
+	}
+	buf.WriteString(xml.Header)
+	return xml.NewEncoder(buf).Encode(feed)
+}
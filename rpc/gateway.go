@@ -0,0 +1,99 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpc
+
+import (
+	"context"
+	_ "embed"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+)
+
+// openAPI is the OpenAPI document of the gateway, generated from
+// defs.proto along with defs.pb.gw.go, see the protoc target of the Makefile.
+//
+//go:embed defs.swagger.json
+var openAPI []byte
+
+// gatewayFormats are the formats of NegotiateFormat that the gateway
+// writes, by the media type that their marshaler is registered for.
+var gatewayFormats = map[string]string{
+	FormatJSON:      "application/json",
+	FormatProto:     "application/x-protobuf",
+	FormatProtoJSON: "application/x-protojson",
+}
+
+// Gateway serves the services registered to its ServeMux, e.g. with
+// RegisterSearchHandlerServer, over HTTP as the google.api.http options
+// of defs.proto map them. The responses are written in the format
+// negotiated as for search results, of JSON, protobuf and protojson only,
+// and the errors by WriteHTTPError.
+type Gateway struct {
+	*runtime.ServeMux
+}
+
+var _ http.Handler = (*Gateway)(nil)
+
+func NewGateway() *Gateway {
+	return &Gateway{
+		ServeMux: runtime.NewServeMux(
+			// Requests are decoded by their Content-Type, JSON if unknown.
+			runtime.WithMarshalerOption(runtime.MIMEWildcard, new(runtime.JSONBuiltin)),
+			runtime.WithMarshalerOption(gatewayFormats[FormatJSON], new(runtime.JSONBuiltin)),
+			runtime.WithMarshalerOption(gatewayFormats[FormatProto], new(protoMarshaler)),
+			runtime.WithMarshalerOption(gatewayFormats[FormatProtoJSON], new(runtime.JSONPb)),
+			runtime.WithProtoErrorHandler(writeGatewayError),
+		),
+	}
+}
+
+func (gw *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	format, err := negotiateFormat(r, FormatJSON, FormatProto, FormatProtoJSON)
+	if err != nil {
+		WriteHTTPError(w, err)
+		return
+	}
+	// The ServeMux picks the marshaler of the exact media type accepted.
+	r.Header.Set("Accept", gatewayFormats[format])
+	w.Header().Add("Vary", "Accept")
+	gw.ServeMux.ServeHTTP(w, r)
+}
+
+// writeGatewayError writes the errors of the services, and those of the
+// ServeMux, whose ErrUnknownURI is the same whether the path or only the
+// method is unknown.
+func writeGatewayError(_ context.Context, _ *runtime.ServeMux, _ runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	if err == runtime.ErrUnknownURI {
+		err = Errorf(CodeNotFound, "No %s %s", r.Method, r.URL.Path)
+	}
+	WriteHTTPError(w, err)
+}
+
+// protoMarshaler is runtime.ProtoMarshaller, with the Content-Type of FormatProto.
+type protoMarshaler struct {
+	runtime.ProtoMarshaller
+}
+
+func (*protoMarshaler) ContentType() string {
+	return contentTypes[FormatProto]
+}
+
+// ServeOpenAPI serves the OpenAPI document of the HTTP mapping of all
+// the services, whichever of them the server registered to its Gateway.
+func ServeOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(openAPI)
+}
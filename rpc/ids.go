@@ -0,0 +1,232 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+// The schemes of the IDs generated. The IDs of every scheme sort, as
+// strings or for Snowflake as numbers, in the order they were generated
+// in by a generator, even if the clock goes back, see NewLocalIDGenerator.
+const (
+	// SchemeXID IDs are 20 character xids, see github.com/rs/xid.
+	SchemeXID = "xid"
+	// SchemeUUIDv7 IDs are RFC 9562 version 7 UUIDs, e.g.
+	// 0190163d-8694-739b-aea5-966c26f8ad91.
+	SchemeUUIDv7 = "uuidv7"
+	// SchemeULID IDs are 26 character ULIDs, see github.com/ulid/spec.
+	SchemeULID = "ulid"
+	// SchemeSnowflake IDs are positive 64 bit integers in decimal made of the
+	// milliseconds since SnowflakeEpoch, a node ID and a sequence number.
+	SchemeSnowflake = "snowflake"
+)
+
+// IDSchemes are the schemes of the IDs that can be generated.
+var IDSchemes = []string{SchemeXID, SchemeUUIDv7, SchemeULID, SchemeSnowflake}
+
+// MaxNodeID is the largest node ID of the Snowflake IDs,
+// which must be unique to each generator to be unique.
+const MaxNodeID = 1<<10 - 1
+
+// SnowflakeEpoch is the time the Snowflake IDs count the milliseconds from.
+var SnowflakeEpoch = time.Date(2018, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// CheckIDScheme returns an error if scheme isn't one of IDSchemes.
+func CheckIDScheme(scheme string) error {
+	for _, s := range IDSchemes {
+		if s == scheme {
+			return nil
+		}
+	}
+	return Errorf(CodeInvalidQuery, "Unknown ID scheme %q, expecting one of %v", scheme, IDSchemes)
+}
+
+// NewLocalIDGenerator returns an IDGenerator of the IDs of scheme generated
+// in process. nodeID, in [0, MaxNodeID], is only used by Snowflake IDs.
+//
+// Each generator keeps the last ID it generated so that the next one sorts
+// after it: within the same millisecond, or the same second for xids, or if
+// the clock went back, the random or counter part is incremented instead.
+func NewLocalIDGenerator(scheme string, nodeID int) (IDGenerator, error) {
+	var src idSource
+	switch scheme {
+	case SchemeXID:
+		src = new(xidSource)
+	case SchemeUUIDv7:
+		src = &randomSource{randBits: 74, format: formatUUIDv7}
+	case SchemeULID:
+		src = &randomSource{randBits: 80, format: formatULID}
+	case SchemeSnowflake:
+		if nodeID < 0 || nodeID > MaxNodeID {
+			return nil, fmt.Errorf("rpc: Snowflake node ID %d is not in [0, %d]", nodeID, MaxNodeID)
+		}
+		src = &snowflakeSource{node: uint64(nodeID)}
+	default:
+		return nil, CheckIDScheme(scheme)
+	}
+	return &monotonicIDGenerator{src: src}, nil
+}
+
+// idSource generates the ID following the last one it generated.
+type idSource interface {
+	next(now time.Time) string
+}
+
+type monotonicIDGenerator struct {
+	mu  sync.Mutex
+	src idSource
+}
+
+func (mg *monotonicIDGenerator) NewID(ctx context.Context) (string, error) {
+	mg.mu.Lock()
+	defer mg.mu.Unlock()
+	return mg.src.next(time.Now()), nil
+}
+
+func (mg *monotonicIDGenerator) NewIDs(ctx context.Context, n int) ([]string, error) {
+	if err := checkIDCount(n); err != nil {
+		return nil, err
+	}
+	ids := make([]string, n)
+	mg.mu.Lock()
+	defer mg.mu.Unlock()
+	now := time.Now()
+	for i := range ids {
+		ids[i] = mg.src.next(now)
+	}
+	return ids, nil
+}
+
+// xidSource keeps the time of the last xid if the clock went back,
+// and bumps it a second ahead if the counter of the xids wrapped around.
+type xidSource struct {
+	last xid.ID
+}
+
+func (xs *xidSource) next(now time.Time) string {
+	id := xid.NewWithTime(now)
+	if id.Compare(xs.last) <= 0 {
+		id = xid.NewWithTime(xs.last.Time())
+	}
+	if id.Compare(xs.last) <= 0 {
+		id = xid.NewWithTime(xs.last.Time().Add(time.Second))
+	}
+	xs.last = id
+	return id.String()
+}
+
+// randomSource generates the IDs made of a 48 bit Unix time in milliseconds
+// followed by randBits random bits. Within the same millisecond the random
+// bits of the last ID are incremented, and once they run out the time is.
+type randomSource struct {
+	randBits uint
+	format   func(ms uint64, hi uint32, lo uint64) string
+
+	ms uint64
+	// hi are the randBits-64 high random bits and lo the 64 low ones.
+	hi uint32
+	lo uint64
+}
+
+func (rs *randomSource) next(now time.Time) string {
+	ms := uint64(now.UnixNano() / int64(time.Millisecond))
+	if ms > rs.ms {
+		rs.ms = ms
+		rs.reseed()
+	} else if rs.lo++; rs.lo == 0 {
+		if rs.hi++; rs.hi == 1<<(rs.randBits-64) {
+			rs.ms++
+			rs.reseed()
+		}
+	}
+	return rs.format(rs.ms, rs.hi, rs.lo)
+}
+
+// reseed picks the random bits, leaving the highest one unset so that
+// many IDs can be incremented from them within the same millisecond.
+func (rs *randomSource) reseed() {
+	var b [10]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("rpc: reading random bytes: %v", err))
+	}
+	rs.hi = uint32(binary.BigEndian.Uint16(b[:2])) & (1<<(rs.randBits-65) - 1)
+	rs.lo = binary.BigEndian.Uint64(b[2:])
+}
+
+// formatUUIDv7 lays the 74 random bits out around the version and variant bits.
+func formatUUIDv7(ms uint64, hi uint32, lo uint64) string {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], ms<<16|0x7<<12|uint64(hi)<<2|lo>>62)
+	binary.BigEndian.PutUint64(b[8:], 0x2<<62|lo&(1<<62-1))
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+	return string(s[:])
+}
+
+// crockford is the Crockford base32 alphabet of the ULIDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// formatULID encodes the 128 bits of the ULID, 5 at a time from the lowest,
+// the highest character only holding the 3 bits left.
+func formatULID(ms uint64, hi uint32, lo uint64) string {
+	var s [26]byte
+	// The low 64 bits, then the 16 high random bits and the 48 bit time.
+	words := [2]uint64{lo, ms<<16 | uint64(hi)}
+	for i := len(s) - 1; i >= 0; i-- {
+		s[i] = crockford[words[0]&0x1f]
+		words[0] = words[0]>>5 | words[1]<<59
+		words[1] >>= 5
+	}
+	return string(s[:])
+}
+
+// snowflakeSource generates 64 bit IDs of 41 bits of milliseconds since
+// SnowflakeEpoch, 10 bits of node ID and 12 bits of sequence number. Once
+// the sequence numbers of a millisecond run out, those of the next one are
+// used, running ahead of the clock until it catches up.
+type snowflakeSource struct {
+	node uint64
+
+	ms  uint64
+	seq uint64
+}
+
+func (ss *snowflakeSource) next(now time.Time) string {
+	ms := uint64(now.Sub(SnowflakeEpoch) / time.Millisecond)
+	if ms > ss.ms {
+		ss.ms, ss.seq = ms, 0
+	} else if ss.seq++; ss.seq == 1<<12 {
+		ss.ms, ss.seq = ss.ms+1, 0
+	}
+	return strconv.FormatUint((ss.ms&(1<<41-1))<<22|ss.node<<12|ss.seq, 10)
+}
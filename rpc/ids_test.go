@@ -0,0 +1,209 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpc
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestSource(t *testing.T, scheme string) idSource {
+	gen, err := NewLocalIDGenerator(scheme, 7)
+	if err != nil {
+		t.Fatalf("NewLocalIDGenerator(%q): %v", scheme, err)
+	}
+	return gen.(*monotonicIDGenerator).src
+}
+
+// idLess reports whether a sorts before b, as numbers for Snowflake IDs.
+func idLess(t *testing.T, scheme, a, b string) bool {
+	if scheme != SchemeSnowflake {
+		return a < b
+	}
+	na, err := strconv.ParseUint(a, 10, 64)
+	if err != nil {
+		t.Fatalf("Snowflake ID %q: %v", a, err)
+	}
+	nb, err := strconv.ParseUint(b, 10, 64)
+	if err != nil {
+		t.Fatalf("Snowflake ID %q: %v", b, err)
+	}
+	return na < nb
+}
+
+func TestIDsIncrease(t *testing.T) {
+	start := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	clocks := []struct {
+		name string
+		at   func(i int) time.Time
+	}{
+		{"ticking", func(i int) time.Time { return start.Add(time.Duration(i) * time.Millisecond) }},
+		{"frozen", func(int) time.Time { return start }},
+		{"backwards", func(i int) time.Time { return start.Add(-time.Duration(i) * time.Millisecond) }},
+		{"jumping back", func(i int) time.Time {
+			if i%100 == 99 {
+				return start.Add(-time.Hour)
+			}
+			return start
+		}},
+	}
+
+	for _, scheme := range IDSchemes {
+		for _, clock := range clocks {
+			src := newTestSource(t, scheme)
+			// More than the 4096 Snowflake sequence numbers of a millisecond.
+			prev := src.next(clock.at(0))
+			for i := 1; i < 10000; i++ {
+				id := src.next(clock.at(i))
+				if !idLess(t, scheme, prev, id) {
+					t.Fatalf("%s, %s clock: ID #%d %q doesn't sort after %q", scheme, clock.name, i, id, prev)
+				}
+				prev = id
+			}
+		}
+	}
+}
+
+func TestUUIDv7Layout(t *testing.T) {
+	now := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	src := newTestSource(t, SchemeUUIDv7)
+	for i := 0; i < 100; i++ {
+		id := src.next(now)
+		if len(id) != 36 {
+			t.Fatalf("%q is %d characters long, want 36", id, len(id))
+		}
+		for _, pos := range []int{8, 13, 18, 23} {
+			if id[pos] != '-' {
+				t.Fatalf("%q has %q at %d, want '-'", id, id[pos], pos)
+			}
+		}
+		if id[14] != '7' {
+			t.Errorf("%q has version %q, want '7'", id, id[14])
+		}
+		if !strings.ContainsRune("89ab", rune(id[19])) {
+			t.Errorf("%q has variant nibble %q, want one of 8, 9, a and b", id, id[19])
+		}
+		ms, err := strconv.ParseUint(id[:8]+id[9:13], 16, 64)
+		if err != nil {
+			t.Fatalf("%q: parsing the time: %v", id, err)
+		}
+		// Within a millisecond, the IDs may run ahead of the clock.
+		if want := uint64(now.UnixNano() / int64(time.Millisecond)); ms < want || ms > want+1 {
+			t.Errorf("%q is of %d ms, want %d", id, ms, want)
+		}
+	}
+}
+
+func TestULIDFormat(t *testing.T) {
+	now := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	src := newTestSource(t, SchemeULID)
+	for i := 0; i < 100; i++ {
+		id := src.next(now)
+		if len(id) != 26 {
+			t.Fatalf("%q is %d characters long, want 26", id, len(id))
+		}
+		for _, c := range id {
+			if !strings.ContainsRune(crockford, c) {
+				t.Fatalf("%q has %q, which isn't Crockford base32", id, c)
+			}
+		}
+		// The first character only holds the 3 high bits of the 128.
+		if id[0] > '7' {
+			t.Errorf("%q overflows 128 bits", id)
+		}
+		var ms uint64
+		for _, c := range id[:10] {
+			ms = ms<<5 | uint64(strings.IndexRune(crockford, c))
+		}
+		if want := uint64(now.UnixNano() / int64(time.Millisecond)); ms < want || ms > want+1 {
+			t.Errorf("%q is of %d ms, want %d", id, ms, want)
+		}
+	}
+}
+
+func TestRandomSourceCarry(t *testing.T) {
+	type bits struct {
+		ms uint64
+		hi uint32
+		lo uint64
+	}
+	const ms = 1000
+	now := time.Unix(0, ms*int64(time.Millisecond))
+
+	tests := []struct {
+		name string
+		last bits
+		want bits
+	}{
+		{"increments lo", bits{ms, 5, 41}, bits{ms, 5, 42}},
+		{"carries lo into hi", bits{ms, 5, 1<<64 - 1}, bits{ms, 6, 0}},
+		{"keeps the time ahead of the clock", bits{ms + 3, 5, 41}, bits{ms + 3, 5, 42}},
+	}
+	for _, tt := range tests {
+		var got bits
+		rs := &randomSource{
+			randBits: 74,
+			format: func(ms uint64, hi uint32, lo uint64) string {
+				got = bits{ms, hi, lo}
+				return ""
+			},
+			ms: tt.last.ms,
+			hi: tt.last.hi,
+			lo: tt.last.lo,
+		}
+		rs.next(now)
+		if got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	// Once the random bits run out, the time is incremented and they are reseeded.
+	var gotMS uint64
+	rs := &randomSource{
+		randBits: 74,
+		format:   func(ms uint64, hi uint32, lo uint64) string { gotMS = ms; return "" },
+		ms:       ms,
+		hi:       1<<10 - 1,
+		lo:       1<<64 - 1,
+	}
+	rs.next(now)
+	if gotMS != ms+1 {
+		t.Errorf("ran out of random bits at %d ms, got %d ms, want %d", ms, gotMS, ms+1)
+	}
+	if rs.hi >= 1<<9 {
+		t.Errorf("reseeded the high random bits to %#x, whose highest bit should be unset", rs.hi)
+	}
+}
+
+func TestSnowflakeLayout(t *testing.T) {
+	now := SnowflakeEpoch.Add(1234 * time.Millisecond)
+	ss := &snowflakeSource{node: 7}
+	for seq := uint64(0); seq < 1<<12+1; seq++ {
+		id, err := strconv.ParseUint(ss.next(now), 10, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The sequence numbers of the millisecond run out after 4096 IDs.
+		wantMS, wantSeq := uint64(1234), seq
+		if seq == 1<<12 {
+			wantMS, wantSeq = 1235, 0
+		}
+		if ms, node, s := id>>22, id>>12&(1<<10-1), id&(1<<12-1); ms != wantMS || node != 7 || s != wantSeq {
+			t.Fatalf("ID #%d is of %d ms, node %d and sequence number %d, want %d, 7 and %d", seq, ms, node, s, wantMS, wantSeq)
+		}
+	}
+}
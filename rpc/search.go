@@ -18,13 +18,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
+	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 
-	gat "google.golang.org/api/googleapi/transport"
 	"google.golang.org/api/youtube/v3"
 
 	"go.opencensus.io/plugin/ochttp"
@@ -32,13 +33,32 @@ import (
 	"go.opencensus.io/tag"
 	"go.opencensus.io/trace"
 
-	"github.com/orijtech/callback"
-	"github.com/orijtech/otils"
+	"github.com/orijtech/media-search/keypool"
+	"github.com/orijtech/media-search/resilience"
+	"github.com/orijtech/media-search/secrets"
+	"github.com/orijtech/media-search/telemetry"
 	yt "github.com/orijtech/youtube"
 )
 
 type Search struct {
 	client *yt.Client
+
+	// svc is used for the YouTube API calls
+	// that *yt.Client doesn't expose e.g. charts.
+	svc *youtube.Service
+
+	// searchUpstream and videosUpstream retry, circuit break
+	// and optionally hedge the calls to the YouTube API.
+	searchUpstream *resilience.Upstream
+	videosUpstream *resilience.Upstream
+
+	hedgeDelay     time.Duration
+	maxHedgedCalls int
+
+	logger *slog.Logger
+
+	detailerURL       string
+	detailerTransport http.RoundTripper
 }
 
 type SearchInitOption interface {
@@ -46,32 +66,126 @@ type SearchInitOption interface {
 }
 
 type withClient struct {
-	yc *yt.Client
+	yc  *yt.Client
+	svc *youtube.Service
 }
 
 var _ SearchInitOption = (*withClient)(nil)
 
 func (wf *withClient) init(ss *Search) {
 	ss.client = wf.yc
+	ss.svc = wf.svc
 }
 
-func WithYouTubeAPIKey(apiKey string) SearchInitOption {
-	yc, err := yt.NewWithHTTPClient(&http.Client{
-		Transport: &ochttp.Transport{Base: &gat.APIKey{Key: apiKey}},
-	})
+// WithYouTubeAPIKey makes the YouTube API calls with a pool of the
+// API keys given, failing over from one to the next as they run out
+// of quota, see WithYouTubeAPIKeyPool.
+func WithYouTubeAPIKey(apiKeys ...string) SearchInitOption {
+	keys, err := secrets.NewKeyring(secrets.Static(apiKeys...))
+	if err != nil {
+		log.Fatalf("WithYouTubeAPIKey: %v", err)
+	}
+	return WithYouTubeAPIKeyPool(keypool.New(keys))
+}
+
+// WithYouTubeAPIKeyPool makes the YouTube API calls with the keys of pool.
+func WithYouTubeAPIKeyPool(pool *keypool.Pool) SearchInitOption {
+	hc := &http.Client{
+		Transport: &ochttp.Transport{Base: pool.Transport(nil)},
+	}
+	yc, err := yt.NewWithHTTPClient(hc)
+	if err != nil {
+		log.Fatalf("WithYouTubeAPIKeyPool: failed to create client, error: %v", err)
+	}
+	svc, err := youtube.New(hc)
 	if err != nil {
-		log.Fatalf("WithYouTubeAPIKey: failed to create client, error: %v", err)
+		log.Fatalf("WithYouTubeAPIKeyPool: failed to create YouTube service, error: %v", err)
 	}
-	return &withClient{yc: yc}
+	return &withClient{yc: yc, svc: svc}
+}
+
+type withHedging struct {
+	delay    time.Duration
+	maxCalls int
+}
+
+var _ SearchInitOption = (*withHedging)(nil)
+
+func (wh *withHedging) init(ss *Search) {
+	ss.hedgeDelay = wh.delay
+	ss.maxHedgedCalls = wh.maxCalls
 }
 
-var videoDetailingHTTPServerURL = otils.EnvOrAlternates("YOUTUBE_DETAILS_HTTP_SERVER_URL", "http://localhost:9944")
+// WithHedging makes up to maxCalls concurrent calls to the YouTube API
+// for every search, each one made after delay without a response.
+// It is meant for trading quota for lower tail latency.
+func WithHedging(delay time.Duration, maxCalls int) SearchInitOption {
+	return &withHedging{delay: delay, maxCalls: maxCalls}
+}
+
+type withLogger struct {
+	logger *slog.Logger
+}
+
+var _ SearchInitOption = (*withLogger)(nil)
+
+func (wl *withLogger) init(ss *Search) {
+	ss.logger = wl.logger
+}
+
+// WithLogger sets the logger of the searches, slog.Default() otherwise.
+func WithLogger(logger *slog.Logger) SearchInitOption {
+	return &withLogger{logger: logger}
+}
+
+type withDetailerURL string
+
+var _ SearchInitOption = withDetailerURL("")
+
+func (wd withDetailerURL) init(ss *Search) {
+	ss.detailerURL = string(wd)
+}
+
+// WithDetailerURL sets the URL of the detailer to which the IDs of the
+// videos found are sent for their details to be fetched in the background.
+func WithDetailerURL(url string) SearchInitOption {
+	return withDetailerURL(url)
+}
+
+type withDetailerTransport struct {
+	rt http.RoundTripper
+}
+
+var _ SearchInitOption = (*withDetailerTransport)(nil)
+
+func (wt *withDetailerTransport) init(ss *Search) {
+	ss.detailerTransport = wt.rt
+}
+
+// WithDetailerTransport sets the transport of the requests to
+// the detailer e.g. for TLS, http.DefaultTransport otherwise.
+func WithDetailerTransport(rt http.RoundTripper) SearchInitOption {
+	return &withDetailerTransport{rt: rt}
+}
 
 func NewSearch(opts ...SearchInitOption) (*Search, error) {
 	ss := new(Search)
 	for _, opt := range opts {
 		opt.init(ss)
 	}
+	if ss.logger == nil {
+		ss.logger = slog.Default()
+	}
+	if ss.detailerTransport == nil {
+		ss.detailerTransport = http.DefaultTransport
+	}
+
+	ss.searchUpstream = resilience.NewUpstream("youtube_search", IsTransient)
+	ss.videosUpstream = resilience.NewUpstream("youtube_videos", IsTransient)
+	for _, u := range []*resilience.Upstream{ss.searchUpstream, ss.videosUpstream} {
+		u.HedgeDelay = ss.hedgeDelay
+		u.MaxHedgedCalls = ss.maxHedgedCalls
+	}
 	return ss, nil
 }
 
@@ -86,7 +200,7 @@ func (ss *Search) SearchIt(ctx context.Context, q *Query) (*SearchResults, error
 
 	// If blank or unset, ensure they are set
 	q.setDefaultLimits()
-	log.Printf("q: %v\n", q)
+	ss.logger.DebugContext(ctx, "Searching", "keywords", q.Keywords, "max_pages", q.MaxPages, "max_results_per_page", q.MaxResultsPerPage)
 
 	ctx, err := tag.New(ctx, tag.Insert(tagKey("service"), "youtube-search"))
 	if err != nil {
@@ -94,23 +208,37 @@ func (ss *Search) SearchIt(ctx context.Context, q *Query) (*SearchResults, error
 	}
 	stats.Record(ctx, youtubeSearches.M(1))
 
-	pagesChan, err := ss.client.Search(ctx, &yt.SearchParam{
-		Query:             q.Keywords,
-		MaxPage:           uint64(q.MaxPages),
-		MaxResultsPerPage: uint64(q.MaxResultsPerPage),
+	start := time.Now()
+	pages, err := ss.searchUpstream.Call(ctx, func(ctx context.Context) (interface{}, error) {
+		return ss.searchPages(ctx, q)
 	})
+	telemetry.RecordLatency(ctx, start, "youtube_search", telemetry.ProviderYouTube, telemetry.ResultOf(err))
 	if err != nil {
 		stats.Record(ctx, youtubeAPIErrors.M(1))
 		span.Annotate([]trace.Attribute{
 			trace.StringAttribute("api_error", err.Error()),
 		}, "YouTube API search error")
-		return nil, err
+		telemetry.RecordError(ctx, err)
+		ss.logger.ErrorContext(ctx, "YouTube API search error", "keywords", q.Keywords, "err", err)
+		return nil, FromError(err)
 	}
 
 	var srl []*SearchResult
 	i := uint64(0)
 	idListForDetails := make([]string, 0, 10)
-	for page := range pagesChan {
+	for _, page := range pages.([]*yt.SearchPage) {
+		if page.Err != nil {
+			// Report the failed page but keep whatever else we got.
+			stats.Record(ctx, youtubeAPIErrors.M(1))
+			e := FromError(page.Err)
+			i += 1
+			srl = append(srl, &SearchResult{
+				Index: i,
+				Err:   e.Error(),
+				Error: e.Detail(),
+			})
+			continue
+		}
 		if len(page.Items) == 0 {
 			continue
 		}
@@ -136,19 +264,69 @@ func (ss *Search) SearchIt(ctx context.Context, q *Query) (*SearchResults, error
 	}
 
 	if len(idListForDetails) > 0 && false {
-		log.Printf("Firing off callback for %#v\n", idListForDetails)
+		ss.logger.DebugContext(ctx, "Firing off the detailing callback", "ids", idListForDetails)
 		// Then fire off the callback to enable background
 		// retrieval of detailed information of found videos.
-		cb := callback.Callback{
-			URL:     videoDetailingHTTPServerURL,
-			Payload: idListForDetails,
-		}
-		cb.Do(ctx)
+		go ss.requestDetails(trace.NewContext(context.Background(), span), idListForDetails)
 	}
 
 	return &SearchResults{Results: srl}, nil
 }
 
+// requestDetails sends ids to the Detail endpoint of the detailer's
+// gateway, for their details to be fetched.
+func (ss *Search) requestDetails(ctx context.Context, ids []string) {
+	blob, err := json.Marshal(&DetailRequest{Ids: ids})
+	if err != nil {
+		ss.logger.ErrorContext(ctx, "Encoding the IDs to detail error", "err", err)
+		return
+	}
+	req, err := http.NewRequest("POST", strings.TrimSuffix(ss.detailerURL, "/")+"/detail", bytes.NewReader(blob))
+	if err != nil {
+		ss.logger.ErrorContext(ctx, "Creating the detailing request error", "err", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	hc := &http.Client{Transport: &ochttp.Transport{Base: ss.detailerTransport}}
+	res, err := hc.Do(req.WithContext(ctx))
+	if err != nil {
+		ss.logger.WarnContext(ctx, "Requesting the details error", "err", err)
+		return
+	}
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		ss.logger.WarnContext(ctx, "Requesting the details error", "status", res.Status)
+	}
+}
+
+// searchPages retrieves all the pages for q. It fails only if the very first
+// page does, since that is the only case in which retrying could help.
+func (ss *Search) searchPages(ctx context.Context, q *Query) ([]*yt.SearchPage, error) {
+	pagesChan, err := ss.client.Search(ctx, &yt.SearchParam{
+		Query:             q.Keywords,
+		MaxPage:           uint64(q.MaxPages),
+		MaxResultsPerPage: uint64(q.MaxResultsPerPage),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var pages []*yt.SearchPage
+	for page := range pagesChan {
+		if page.Err != nil && len(pages) == 0 {
+			// Unblock the producer before giving up.
+			go func() {
+				for range pagesChan {
+				}
+			}()
+			return nil, page.Err
+		}
+		pages = append(pages, page)
+	}
+	return pages, nil
+}
+
 func tagKey(key string) tag.Key {
 	k, _ := tag.NewKey(key)
 	return k
@@ -174,13 +352,20 @@ func toSearchSnippet(snip *youtube.SearchResultSnippet) *YouTubeSnippet {
 		Description:  snip.Description,
 		PublishedAt:  snip.PublishedAt,
 		Title:        snip.Title,
-		Thumbnails: map[string]*Thumbnail{
-			"default":  toThumbnail(snip.Thumbnails.Default),
-			"high":     toThumbnail(snip.Thumbnails.High),
-			"maxres":   toThumbnail(snip.Thumbnails.Maxres),
-			"medium":   toThumbnail(snip.Thumbnails.Medium),
-			"standard": toThumbnail(snip.Thumbnails.Standard),
-		},
+		Thumbnails:   toThumbnails(snip.Thumbnails),
+	}
+}
+
+func toThumbnails(td *youtube.ThumbnailDetails) map[string]*Thumbnail {
+	if td == nil {
+		return nil
+	}
+	return map[string]*Thumbnail{
+		"default":  toThumbnail(td.Default),
+		"high":     toThumbnail(td.High),
+		"maxres":   toThumbnail(td.Maxres),
+		"medium":   toThumbnail(td.Medium),
+		"standard": toThumbnail(td.Standard),
 	}
 }
 
@@ -195,24 +380,30 @@ func toThumbnail(th *youtube.Thumbnail) *Thumbnail {
 	}
 }
 
-// And for HTTP based RPCs
+// And for HTTP based RPCs, in the format negotiated, see NegotiateFormat.
+// It is served at /search instead of the gateway's mapping of SearchIt,
+// which only writes JSON, protobuf and protojson.
 func (ss *Search) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx, span := trace.StartSpan(r.Context(), "/search")
 	defer span.End()
 
 	q, err := ExtractQuery(ctx, r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		WriteHTTPError(w, err)
+		return
+	}
+	format, err := NegotiateFormat(r)
+	if err != nil {
+		WriteHTTPError(w, err)
 		return
 	}
 
 	results, err := ss.SearchIt(ctx, q)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteHTTPError(w, err)
 		return
 	}
-	enc := json.NewEncoder(w)
-	_ = enc.Encode(results)
+	WriteSearchResults(w, r, format, q, results)
 }
 
 func ExtractQuery(ctx context.Context, r *http.Request) (*Query, error) {
@@ -223,7 +414,7 @@ func ExtractQuery(ctx context.Context, r *http.Request) (*Query, error) {
 
 	switch r.Method {
 	default:
-		return nil, fmt.Errorf("Unacceptable method %q", r.Method)
+		return nil, Errorf(CodeMethodNotAllowed, "Unacceptable method %q", r.Method)
 
 	case "PUT", "POST":
 		defer r.Body.Close()
@@ -243,7 +434,7 @@ func ExtractQuery(ctx context.Context, r *http.Request) (*Query, error) {
 		}
 		intermediateBlob, err := json.Marshal(outMap)
 		if err != nil {
-			return nil, err
+			return nil, Errorf(CodeInvalidQuery, "%v", err)
 		}
 		body = bytes.NewReader(intermediateBlob)
 		span.Annotate([]trace.Attribute{
@@ -260,11 +451,11 @@ parseJSON:
 	// By this point we are extracting only JSON.
 	blob, err := ioutil.ReadAll(body)
 	if err != nil {
-		return nil, err
+		return nil, Errorf(CodeInvalidQuery, "%v", err)
 	}
 	qy := new(Query)
 	if err := json.Unmarshal(blob, qy); err != nil {
-		return nil, err
+		return nil, Errorf(CodeInvalidQuery, "%v", err)
 	}
 	qy.setDefaultLimits()
 	return qy, nil
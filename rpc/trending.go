@@ -0,0 +1,207 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/youtube/v3"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+	"go.opencensus.io/trace"
+
+	"github.com/orijtech/media-search/telemetry"
+)
+
+var youtubeTrendingLookups = stats.Int64("youtube_trending_lookups", "The number of YouTube mostPopular chart lookups", "1")
+
+var errNoYouTubeService = Errorf(CodeUpstreamUnavailable, "no YouTube service configured")
+
+// Trending retrieves YouTube's mostPopular chart for
+// the region and video category requested in tq.
+func (ss *Search) Trending(ctx context.Context, tq *TrendingQuery) (*TrendingResults, error) {
+	ctx, span := trace.StartSpan(ctx, "trending")
+	defer span.End()
+
+	if ss.svc == nil {
+		return nil, errNoYouTubeService
+	}
+
+	// As the gateway passes the query parameters on as they are.
+	tq.RegionCode = strings.ToUpper(strings.TrimSpace(tq.RegionCode))
+	tq.CategoryId = strings.TrimSpace(tq.CategoryId)
+	if err := tq.validate(); err != nil {
+		return nil, err
+	}
+	// If blank or unset, ensure they are set
+	tq.setDefaultLimits()
+
+	ctx, err := tag.New(ctx, tag.Insert(tagKey("service"), "youtube-trending"))
+	if err != nil {
+		return nil, err
+	}
+	stats.Record(ctx, youtubeTrendingLookups.M(1))
+
+	span.Annotate([]trace.Attribute{
+		trace.StringAttribute("region_code", tq.RegionCode),
+		trace.StringAttribute("category_id", tq.CategoryId),
+	}, "Fetching the mostPopular chart")
+
+	start := time.Now()
+	res, err := ss.videosUpstream.Call(ctx, func(ctx context.Context) (interface{}, error) {
+		// Every call gets its own *youtube.VideosListCall since hedged calls run concurrently.
+		res, err := ss.trendingCall(tq).Context(ctx).Do()
+		if err != nil {
+			return nil, err
+		}
+		return res, nil
+	})
+	telemetry.RecordLatency(ctx, start, "youtube_trending", telemetry.ProviderYouTube, telemetry.ResultOf(err))
+	if err != nil {
+		stats.Record(ctx, youtubeAPIErrors.M(1))
+		span.Annotate([]trace.Attribute{
+			trace.StringAttribute("api_error", err.Error()),
+		}, "YouTube API trending error")
+		telemetry.RecordError(ctx, err)
+		ss.logger.ErrorContext(ctx, "YouTube API trending error", "region_code", tq.RegionCode, "category_id", tq.CategoryId, "err", err)
+		return nil, FromError(err)
+	}
+
+	videos := res.(*youtube.VideoListResponse).Items
+	items := make([]*YouTubeResult, 0, len(videos))
+	for _, video := range videos {
+		if video != nil {
+			items = append(items, videoToResult(video))
+		}
+	}
+
+	return &TrendingResults{
+		RegionCode: tq.RegionCode,
+		CategoryId: tq.CategoryId,
+		Items:      items,
+	}, nil
+}
+
+func (ss *Search) trendingCall(tq *TrendingQuery) *youtube.VideosListCall {
+	call := ss.svc.Videos.List([]string{"snippet"}).Chart("mostPopular").MaxResults(int64(tq.MaxResults))
+	if tq.RegionCode != "" {
+		call = call.RegionCode(tq.RegionCode)
+	}
+	if tq.CategoryId != "" {
+		call = call.VideoCategoryId(tq.CategoryId)
+	}
+	return call
+}
+
+func videoToResult(v *youtube.Video) *YouTubeResult {
+	yr := &YouTubeResult{
+		Etag: v.Etag,
+		Kind: v.Kind,
+		Id: &YouTubeID{
+			Kind:    v.Kind,
+			VideoId: v.Id,
+		},
+	}
+	if snip := v.Snippet; snip != nil {
+		yr.Snippet = &YouTubeSnippet{
+			ChannelTitle: snip.ChannelTitle,
+			ChannelId:    snip.ChannelId,
+			Description:  snip.Description,
+			PublishedAt:  snip.PublishedAt,
+			Title:        snip.Title,
+			Thumbnails:   toThumbnails(snip.Thumbnails),
+		}
+	}
+	return yr
+}
+
+// ExtractTrendingQuery parses the "regionCode", "categoryId"
+// and "maxResults" URL query parameters of a GET request.
+func ExtractTrendingQuery(ctx context.Context, r *http.Request) (*TrendingQuery, error) {
+	_, span := trace.StartSpan(ctx, "/extract-trending-query")
+	defer span.End()
+
+	if r.Method != "GET" {
+		return nil, Errorf(CodeMethodNotAllowed, "Unacceptable method %q", r.Method)
+	}
+
+	qv := r.URL.Query()
+	tq := &TrendingQuery{
+		RegionCode: strings.ToUpper(strings.TrimSpace(qv.Get("regionCode"))),
+		CategoryId: strings.TrimSpace(qv.Get("categoryId")),
+	}
+	if s := qv.Get("maxResults"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return nil, Errorf(CodeInvalidQuery, "maxResults: %v", err)
+		}
+		tq.MaxResults = int32(n)
+	}
+	if err := tq.validate(); err != nil {
+		return nil, err
+	}
+	tq.setDefaultLimits()
+	return tq, nil
+}
+
+// MaxTrendingResults is the most results that the mostPopular chart returns per page.
+const MaxTrendingResults = 50
+
+// CacheKey returns the key under which the chart for tq is cached. It
+// leaves out MaxResults, the chart is cached with MaxTrendingResults
+// and every smaller page of it is sliced from that.
+func (tq *TrendingQuery) CacheKey() string {
+	return fmt.Sprintf("%s/%s", tq.RegionCode, tq.CategoryId)
+}
+
+// validate rejects the region codes and video category IDs that YouTube
+// would, before they cost a lookup or end up as keys of the cache.
+func (tq *TrendingQuery) validate() error {
+	if tq.RegionCode != "" && !regionCodes[tq.RegionCode] {
+		return Errorf(CodeInvalidQuery, "regionCode %q is not an ISO 3166-1 alpha-2 code", tq.RegionCode)
+	}
+	if !isCategoryID(tq.CategoryId) {
+		return Errorf(CodeInvalidQuery, "categoryId %q is not a video category ID", tq.CategoryId)
+	}
+	return nil
+}
+
+// isCategoryID reports whether s is blank or a video category ID,
+// which are numbered from 1 and in the tens so far.
+func isCategoryID(s string) bool {
+	if len(s) > 3 {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (tq *TrendingQuery) setDefaultLimits() {
+	if tq.MaxResults <= 0 {
+		tq.MaxResults = 25
+	} else if tq.MaxResults > MaxTrendingResults {
+		tq.MaxResults = MaxTrendingResults
+	}
+}
@@ -16,34 +16,184 @@ package rpc
 
 import (
 	"context"
-	"encoding/json"
 	"net/http"
 
-	"github.com/rs/xid"
 	"go.opencensus.io/trace"
 )
 
-type GID int
+// MaxIDsPerCall bounds how many IDs a single call to NewIDs may ask for.
+const MaxIDsPerCall = 1000
+
+// IDGenerator generates globally unique IDs.
+type IDGenerator interface {
+	NewID(ctx context.Context) (string, error)
+	// NewIDs returns n IDs at once, n in [1, MaxIDsPerCall].
+	NewIDs(ctx context.Context, n int) ([]string, error)
+}
+
+// LocalIDGenerator returns an IDGenerator of xids generated in process,
+// which are unique across processes without any coordination.
+func LocalIDGenerator() IDGenerator {
+	gen, _ := NewLocalIDGenerator(SchemeXID, 0)
+	return gen
+}
+
+// RemoteIDGenerator returns an IDGenerator calling the GenID service through
+// client for IDs of scheme, or of the default scheme of the service if blank.
+func RemoteIDGenerator(client GenIDClient, scheme string) IDGenerator {
+	return &remoteIDGenerator{client: client, scheme: scheme}
+}
+
+type remoteIDGenerator struct {
+	client GenIDClient
+	scheme string
+}
+
+func (rg *remoteIDGenerator) NewID(ctx context.Context) (string, error) {
+	id, err := rg.client.NewID(ctx, &IDRequest{Scheme: rg.scheme})
+	if err != nil {
+		return "", err
+	}
+	return id.Value, nil
+}
+
+func (rg *remoteIDGenerator) NewIDs(ctx context.Context, n int) ([]string, error) {
+	if err := checkIDCount(n); err != nil {
+		return nil, err
+	}
+	ids, err := rg.client.NewIDs(ctx, &IDsRequest{Count: int32(n), Scheme: rg.scheme})
+	if err != nil {
+		return nil, err
+	}
+	return ids.Values, nil
+}
+
+func checkIDCount(n int) error {
+	if n < 1 || n > MaxIDsPerCall {
+		return Errorf(CodeInvalidQuery, "Expecting a count of IDs in [1, %d], got %d", MaxIDsPerCall, n)
+	}
+	return nil
+}
+
+// GID serves the IDs generated in process as the GenID service,
+// of the scheme requested or otherwise of its default scheme.
+type GID struct {
+	defaultScheme string
+	nodeID        int
+
+	gens map[string]IDGenerator
+}
 
 var _ GenIDServer = (*GID)(nil)
 
-func NewGenID() (*GID, error) {
-	return new(GID), nil
+type GenIDInitOption interface {
+	init(*GID)
+}
+
+type withDefaultIDScheme string
+
+var _ GenIDInitOption = withDefaultIDScheme("")
+
+func (ws withDefaultIDScheme) init(gi *GID) {
+	gi.defaultScheme = string(ws)
 }
 
-func (gi *GID) NewID(ctx context.Context, _ *Nothing) (*ID, error) {
+// WithDefaultIDScheme sets the scheme of the IDs of the
+// requests that don't ask for one, SchemeXID otherwise.
+func WithDefaultIDScheme(scheme string) GenIDInitOption {
+	return withDefaultIDScheme(scheme)
+}
+
+type withNodeID int
+
+var _ GenIDInitOption = withNodeID(0)
+
+func (wn withNodeID) init(gi *GID) {
+	gi.nodeID = int(wn)
+}
+
+// WithNodeID sets the node ID, in [0, MaxNodeID], of the Snowflake IDs,
+// which must differ between the replicas of the service, 0 otherwise.
+func WithNodeID(nodeID int) GenIDInitOption {
+	return withNodeID(nodeID)
+}
+
+// NewGenID returns the GenID service of the IDs generated in process.
+func NewGenID(opts ...GenIDInitOption) (*GID, error) {
+	gi := &GID{defaultScheme: SchemeXID, gens: make(map[string]IDGenerator)}
+	for _, opt := range opts {
+		opt.init(gi)
+	}
+	if err := CheckIDScheme(gi.defaultScheme); err != nil {
+		return nil, err
+	}
+	for _, scheme := range IDSchemes {
+		gen, err := NewLocalIDGenerator(scheme, gi.nodeID)
+		if err != nil {
+			return nil, err
+		}
+		gi.gens[scheme] = gen
+	}
+	return gi, nil
+}
+
+// generator returns the generator of the IDs of scheme, or of
+// the default scheme if blank, and CodeInvalidQuery if unknown.
+func (gi *GID) generator(scheme string) (IDGenerator, error) {
+	if scheme == "" {
+		scheme = gi.defaultScheme
+	}
+	gen, ok := gi.gens[scheme]
+	if !ok {
+		return nil, CheckIDScheme(scheme)
+	}
+	return gen, nil
+}
+
+func (gi *GID) NewID(ctx context.Context, req *IDRequest) (*ID, error) {
 	ctx, span := trace.StartSpan(ctx, "newID")
 	defer span.End()
+	span.AddAttributes(trace.StringAttribute("scheme", req.GetScheme()))
 
-	return &ID{Value: xid.New().String()}, nil
+	gen, err := gi.generator(req.GetScheme())
+	if err != nil {
+		return nil, err
+	}
+	id, err := gen.NewID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ID{Value: id}, nil
 }
 
-func (gi *GID) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	id, err := gi.NewID(r.Context(), nil)
+func (gi *GID) NewIDs(ctx context.Context, req *IDsRequest) (*IDs, error) {
+	ctx, span := trace.StartSpan(ctx, "newIDs")
+	defer span.End()
+	span.AddAttributes(
+		trace.Int64Attribute("count", int64(req.GetCount())),
+		trace.StringAttribute("scheme", req.GetScheme()),
+	)
+
+	gen, err := gi.generator(req.GetScheme())
+	if err != nil {
+		return nil, err
+	}
+	ids, err := gen.NewIDs(ctx, int(req.GetCount()))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return nil, err
 	}
-	enc := json.NewEncoder(w)
-	enc.Encode(id)
+	return &IDs{Values: ids}, nil
+}
+
+// LegacyIDs serves GET /id?count=n, which served a batch of IDs before
+// the gateway did, as GET /ids?count=n by h, e.g. a Gateway, and any
+// other request as it is.
+func LegacyIDs(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/id" && r.URL.Query().Get("count") != "" {
+			r = r.Clone(r.Context())
+			r.URL.Path, r.URL.RawPath = "/ids", ""
+		}
+		h.ServeHTTP(w, r)
+	})
 }
@@ -0,0 +1,202 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package secrets loads secrets such as API keys from the environment, files
+// and mounted secret directories, and reloads them so that they can be
+// rotated without restarting. Secrets are never read from flags or the
+// configuration file, which end up in process listings and -print-config.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/orijtech/media-search/config"
+)
+
+// Config selects the sources of a kind of secret.
+type Config struct {
+	// Name names the secrets in the flags, e.g. youtube-api-key
+	// for -youtube-api-key-file and -youtube-api-key-dir.
+	Name string
+	// Env, if set, is the environment variable holding comma separated secrets.
+	Env string
+
+	// File and Dir, if set, are read like the sources of the same name.
+	File string
+	Dir  string
+
+	ReloadInterval time.Duration
+}
+
+// Fields returns the settings of c, to be loaded by a config.Loader.
+func (c *Config) Fields() []*config.Field {
+	env := "MEDIA_SEARCH_" + strings.ToUpper(strings.Replace(c.Name, "-", "_", -1))
+	return []*config.Field{
+		{Name: c.Name + "-file", Env: env + "_FILE", Usage: "the path to a file holding the " + c.Name + "s, one per line", Value: &c.File},
+		{Name: c.Name + "-dir", Env: env + "_DIR", Usage: "the path to a directory, e.g. a mounted secret, whose files hold the " + c.Name + "s", Value: &c.Dir},
+		{Name: c.Name + "-reload-interval", Env: env + "_RELOAD_INTERVAL", Usage: "how often the " + c.Name + "s are reloaded, which they also are on SIGHUP", Value: &c.ReloadInterval},
+	}
+}
+
+// Validate returns an error if the reload interval isn't positive.
+func (c *Config) Validate() error {
+	return config.CheckPositive(c.Name+"-reload-interval", c.ReloadInterval)
+}
+
+// Sources returns the sources configured.
+func (c *Config) Sources() []Source {
+	var sources []Source
+	if c.Env != "" {
+		sources = append(sources, Env(c.Env))
+	}
+	if c.File != "" {
+		sources = append(sources, File(c.File))
+	}
+	if c.Dir != "" {
+		sources = append(sources, Dir(c.Dir))
+	}
+	return sources
+}
+
+// Configured reports whether the environment variable is set or a file
+// or directory is, for secrets that are optional.
+func (c *Config) Configured() bool {
+	if c.Env != "" {
+		if _, ok := os.LookupEnv(c.Env); ok {
+			return true
+		}
+	}
+	return c.File != "" || c.Dir != ""
+}
+
+// NewKeyring returns a Keyring of the sources configured. Its error
+// tells how to configure a source if none holds any secrets.
+func (c *Config) NewKeyring() (*Keyring, error) {
+	kr, err := NewKeyring(c.Sources()...)
+	if err == errNoSecrets {
+		return nil, fmt.Errorf("secrets: no %s configured, set %s to a comma separated list of them or pass -%s-file or -%s-dir",
+			c.Name, c.Env, c.Name, c.Name)
+	}
+	return kr, err
+}
+
+// Keyring holds the secrets of its sources.
+type Keyring struct {
+	sources []Source
+
+	mu   sync.RWMutex
+	keys []string
+}
+
+var errNoSecrets = errors.New("secrets: none found")
+
+// NewKeyring returns a Keyring of the secrets held by sources,
+// failing if any source can't be loaded or they hold none.
+func NewKeyring(sources ...Source) (*Keyring, error) {
+	kr := &Keyring{sources: sources}
+	if err := kr.Reload(); err != nil {
+		return nil, err
+	}
+	return kr, nil
+}
+
+// Reload replaces the secrets with those the sources now hold. If a source
+// fails to load or they hold none, the current secrets are kept instead.
+func (kr *Keyring) Reload() error {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, source := range kr.sources {
+		loaded, err := source.Load()
+		if err != nil {
+			return fmt.Errorf("secrets: loading %s: %v", source, err)
+		}
+		for _, key := range loaded {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return errNoSecrets
+	}
+
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	if !sameKeys(keys, kr.keys) {
+		if kr.keys != nil {
+			slog.Info("Reloaded the secrets", "sources", kr.describeSources(), "count", len(keys))
+		}
+		kr.keys = keys
+	}
+	return nil
+}
+
+func sameKeys(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (kr *Keyring) describeSources() string {
+	descriptions := make([]string, 0, len(kr.sources))
+	for _, source := range kr.sources {
+		descriptions = append(descriptions, source.String())
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// Keys returns the secrets in the order of their sources, without duplicates.
+func (kr *Keyring) Keys() []string {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	return append([]string(nil), kr.keys...)
+}
+
+// Watch reloads the secrets every interval and whenever the process
+// receives SIGHUP, until ctx is done. A failed reload is only logged.
+func (kr *Keyring) Watch(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-hup:
+		}
+		if err := kr.Reload(); err != nil {
+			slog.ErrorContext(ctx, "Reloading the secrets error, keeping the current ones", "sources", kr.describeSources(), "err", err)
+		}
+	}
+}
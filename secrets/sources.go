@@ -0,0 +1,125 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Source is a place secrets are loaded from.
+type Source interface {
+	// Load returns the secrets currently held by the source.
+	Load() ([]string, error)
+
+	// String describes the source for error messages,
+	// without revealing any of its secrets.
+	String() string
+}
+
+// Static returns a Source always holding secrets.
+func Static(secrets ...string) Source {
+	return staticSource(secrets)
+}
+
+type staticSource []string
+
+func (ss staticSource) Load() ([]string, error) { return ss, nil }
+
+func (ss staticSource) String() string { return fmt.Sprintf("%d static secrets", len(ss)) }
+
+// Env returns a Source holding the comma separated secrets of the
+// environment variable name, which holds none if it is unset.
+func Env(name string) Source {
+	return envSource(name)
+}
+
+type envSource string
+
+func (es envSource) Load() ([]string, error) {
+	return split(os.Getenv(string(es)), ","), nil
+}
+
+func (es envSource) String() string { return "env " + string(es) }
+
+// File returns a Source holding the secrets of the file at path, one per
+// line, ignoring blank lines and those starting with #. It is an error
+// for the file not to exist.
+func File(path string) Source {
+	return fileSource(path)
+}
+
+type fileSource string
+
+func (fs fileSource) Load() ([]string, error) {
+	blob, err := ioutil.ReadFile(string(fs))
+	if err != nil {
+		return nil, err
+	}
+	return split(string(blob), "\n"), nil
+}
+
+func (fs fileSource) String() string { return "file " + string(fs) }
+
+// Dir returns a Source holding the secrets of every file in the directory
+// at path, read like File, e.g. a Kubernetes secret mounted as a volume.
+// Hidden files are skipped, such as those Kubernetes uses to swap the
+// whole directory atomically on update.
+func Dir(path string) Source {
+	return dirSource(path)
+}
+
+type dirSource string
+
+func (ds dirSource) Load() ([]string, error) {
+	infos, err := ioutil.ReadDir(string(ds))
+	if err != nil {
+		return nil, err
+	}
+	var secrets []string
+	for _, info := range infos {
+		if strings.HasPrefix(info.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(string(ds), info.Name())
+		// Mounted secrets are symlinks, so look past them.
+		if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		fileSecrets, err := fileSource(path).Load()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", ds, err)
+		}
+		secrets = append(secrets, fileSecrets...)
+	}
+	return secrets, nil
+}
+
+func (ds dirSource) String() string { return "dir " + string(ds) }
+
+func split(s, sep string) []string {
+	var secrets []string
+	for _, secret := range strings.Split(s, sep) {
+		secret = strings.TrimSpace(secret)
+		if secret == "" || strings.HasPrefix(secret, "#") {
+			continue
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets
+}
@@ -1,5 +1,6 @@
 var nodes = {
 	searchInput: document.querySelector('.js-search-input'),
+	searchSuggestions: document.querySelector('.js-search-suggestions'),
 	searchButton: document.querySelector('.js-search-button'),
 	searchSection: document.querySelector('.js-search-section'),
 	resultsSection: document.querySelector('.js-results-section'),
@@ -30,7 +31,7 @@ function sendRequest(object) {
 	xhr.onreadystatechange = function() {
 		if (xhr.readyState === 4) {
 			if (xhr.status === 200) {
-				return successCallback(JSON.parse(xhr.responseText));
+				return successCallback(xhr.responseText);
 			} else {
 				return errorCallback(xhr.status);
 			}
@@ -104,39 +105,17 @@ function appendResult(result) {
 	}
 }
 
-function successCallback(response) {
+function successCallback(responseText) {
 	onSearchEnd();
 
-	var results = response[0].items.map(function(item) {
-		var url = 'https://youtube.com/';
-		var resultType = null;
-		var thumbnail = null;
-		var id = null;
-
-		if (item.id.videoId) {
-			id = item.id.videoId;
-			resultType = 'video';
-			url = url + 'watch?v=' + item.id.videoId;
-		} else {
-			id = item.id.channelId;
-			resultType = 'channel';
-			url = url + 'channel/' + item.id.channelId;
-		}
-
-		if (item.snippet.thumbnails.high) {
-			thumbnail = item.snippet.thumbnails.high.url;
-		} else if (item.snippet.thumbnail.medium) {
-			thumbnail = item.snippet.thumbnails.medium.url;
-		} else {
-			item.snippet.thumbnails.default.url;
-		}
-
+	var results = parseSearchResponse(responseText).items.map(function(item) {
+		var thumbnail = bestThumbnail(item);
 		return {
-			id: id,
-			url: url,
-			title: item.snippet.title,
-			thumbnail: thumbnail,
-			resultType: resultType
+			id: item.id,
+			url: item.url,
+			title: item.title,
+			thumbnail: thumbnail === null ? '' : thumbnail.url,
+			resultType: item.kind
 		};
 	});
 
@@ -162,7 +141,7 @@ function onSearchClick() {
 	sendRequest({
 		method: 'POST',
 		data: {"keywords": query, "maxResultsPerPage": 25},
-		url: window.location.origin + '/search',
+		url: window.location.origin + '/v1/search',
 		successCallback: successCallback,
 		errorCallback: errorCallback
 	});
@@ -203,4 +182,33 @@ function showInlinePlayer(youtubeId) {
 	});
 }
 
+function showSuggestions(response) {
+	while (nodes.searchSuggestions.firstChild) {
+		nodes.searchSuggestions.removeChild(nodes.searchSuggestions.firstChild);
+	}
+
+	response.suggestions.forEach(function(suggestion) {
+		var option = document.createElement('option');
+		option.value = suggestion.query;
+		nodes.searchSuggestions.appendChild(option);
+	});
+}
+
+function onSearchInput() {
+	var query = nodes.searchInput.value.trim();
+	if (!query) {
+		return;
+	}
+
+	sendRequest({
+		method: 'GET',
+		url: window.location.origin + '/suggest?q=' + encodeURIComponent(query),
+		successCallback: function(responseText) {
+			showSuggestions(JSON.parse(responseText));
+		},
+		errorCallback: function() {}
+	});
+}
+
 nodes.searchButton.addEventListener('click', onSearchClick)
+nodes.searchInput.addEventListener('input', onSearchInput)
@@ -0,0 +1,65 @@
+// Parses the responses of /v1/search, see api/v1/search.schema.json. Loaded
+// by index.html, and run by node for clients/conformance:
+//
+//	node static/v1.js response.json
+
+function parseSearchResponse(text) {
+	var response = JSON.parse(text);
+	if (response.version !== 'v1') {
+		throw new Error('unsupported version ' + JSON.stringify(response.version));
+	}
+	return response;
+}
+
+// bestThumbnail returns the first of the high, medium and default
+// thumbnails of item that isn't null, or null if it has none.
+function bestThumbnail(item) {
+	var thumbnails = item.thumbnails;
+	return thumbnails.high || thumbnails.medium || thumbnails.default || null;
+}
+
+function quote(s) {
+	return '"' + s.replace(/\\/g, '\\\\').replace(/"/g, '\\"').replace(/\n/g, '\\n').replace(/\r/g, '\\r').replace(/\t/g, '\\t') + '"';
+}
+
+function number(n) {
+	return n === null ? 'null' : String(n);
+}
+
+// conformanceLines returns the lines of response in the form that every
+// client prints a response in, see clients/conformance/README.md.
+function conformanceLines(response) {
+	var lines = ['query: ' + quote(response.query)];
+	response.items.forEach(function(item) {
+		var th = bestThumbnail(item);
+		lines.push(
+			'item',
+			'kind: ' + quote(item.kind),
+			'id: ' + quote(item.id),
+			'url: ' + quote(item.url),
+			'title: ' + quote(item.title),
+			'description: ' + quote(item.description),
+			'published_at: ' + (item.published_at === null ? 'null' : quote(item.published_at)),
+			'channel_id: ' + quote(item.channel.id),
+			'channel_title: ' + quote(item.channel.title),
+			'thumbnail: ' + (th === null ? 'null' : quote(th.url)),
+			'thumbnail_width: ' + (th === null ? 'null' : number(th.width)),
+			'thumbnail_height: ' + (th === null ? 'null' : number(th.height)),
+			'page: ' + item.page
+		);
+	});
+	response.errors.forEach(function(error) {
+		lines.push(
+			'error',
+			'page: ' + error.page,
+			'code: ' + quote(error.code),
+			'message: ' + quote(error.message)
+		);
+	});
+	return lines;
+}
+
+if (typeof module !== 'undefined' && require.main === module) {
+	var text = require('fs').readFileSync(process.argv[2], 'utf8');
+	process.stdout.write(conformanceLines(parseSearchResponse(text)).join('\n') + '\n');
+}
@@ -0,0 +1,259 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/mongo"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/trace"
+
+	"github.com/orijtech/media-search/rpc"
+)
+
+var searchLogCollection *mongo.Collection
+
+// suggestRefreshInterval and suggestLookback are set
+// from the configuration, see frontendConfig.
+var (
+	suggestRefreshInterval time.Duration
+	suggestLookback        time.Duration
+)
+
+const (
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 25
+)
+
+var suggestRefreshes = stats.Int64("suggest_refreshes", "the number of suggestion trie rebuilds", stats.UnitNone)
+
+// suggestions holds the most recently built trie. Lookups only
+// ever read from it, so serving suggestions costs no upstream quota.
+var suggestions = new(suggestionIndex)
+
+func suggest(w http.ResponseWriter, r *http.Request) {
+	_, span := trace.StartSpan(r.Context(), "/suggest")
+	defer span.End()
+
+	qv := r.URL.Query()
+	prefix := normalizeQuery(qv.Get("q"))
+	limit := defaultSuggestLimit
+	if s := qv.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			rpc.WriteHTTPError(w, rpc.Errorf(rpc.CodeInvalidQuery, "Expecting a positive integer limit"))
+			return
+		}
+		limit = n
+	}
+	if limit > maxSuggestLimit {
+		limit = maxSuggestLimit
+	}
+
+	var matches []*suggestion
+	if prefix != "" {
+		matches = suggestions.lookup(prefix, limit)
+	}
+	span.Annotate([]trace.Attribute{
+		trace.StringAttribute("prefix", prefix),
+		trace.Int64Attribute("matches", int64(len(matches))),
+	}, "Looked up suggestions")
+
+	if matches == nil {
+		matches = []*suggestion{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	_ = enc.Encode(map[string]interface{}{
+		"query":       prefix,
+		"suggestions": matches,
+	})
+}
+
+// refreshSuggestionsPeriodically rebuilds the suggestion trie
+// from storage every suggestRefreshInterval, forever.
+func refreshSuggestionsPeriodically() {
+	for {
+		if err := refreshSuggestions(context.Background()); err != nil {
+			logger.Error("Refreshing suggestions error", "err", err)
+		}
+		<-time.After(suggestRefreshInterval)
+	}
+}
+
+func refreshSuggestions(ctx context.Context) error {
+	ctx, span := trace.StartSpan(ctx, "refresh-suggestions")
+	defer span.End()
+
+	// 1. The search log has an event for every time a query was issued
+	// within the lookback, but neither those before it nor those from
+	// before the log was kept.
+	qcl, err := countSearchKeys(ctx, time.Now().Add(-suggestLookback), 0)
+	if err != nil {
+		return err
+	}
+	counts := make(map[string]int64, len(qcl))
+	for _, qc := range qcl {
+		counts[qc.Key] = qc.Count
+	}
+
+	// 2. Every cached query was issued at least once, which is only
+	// counted if the search log has fewer, not to count it twice.
+	cached, err := countCachedQueries(ctx)
+	if err != nil {
+		return err
+	}
+	for key, count := range cached {
+		if count > counts[key] {
+			counts[key] = count
+		}
+	}
+
+	suggestions.replace(newSuggestionTrie(counts))
+	stats.Record(ctx, suggestRefreshes.M(1))
+	span.Annotate([]trace.Attribute{
+		trace.Int64Attribute("queries", int64(len(counts))),
+	}, "Rebuilt the suggestion trie")
+	return nil
+}
+
+// countCachedQueries counts the distinct cached queries by their
+// canonical form, see normalizeQuery. MongoDB groups the cache entries
+// by their key, so only the keys are ever read, not the results.
+func countCachedQueries(ctx context.Context) (map[string]int64, error) {
+	pipeline := bson.NewArray(
+		bson.VC.DocumentFromElements(bson.EC.SubDocumentFromElements("$group", bson.EC.String("_id", "$key"))),
+	)
+	cur, err := ytSearchesCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		stats.Record(ctx, mongoErrors.M(1))
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	counts := make(map[string]int64)
+	for cur.Next(ctx) {
+		qc := new(queryCount)
+		if err := cur.Decode(qc); err == nil {
+			if key := normalizeQuery(qc.Key); key != "" {
+				counts[key] += 1
+			}
+		}
+	}
+	return counts, cur.Err()
+}
+
+// normalizeQuery lowercases q and collapses its whitespace
+// so that trivially different spellings are counted together.
+func normalizeQuery(q string) string {
+	return strings.Join(strings.Fields(strings.ToLower(q)), " ")
+}
+
+type suggestion struct {
+	Query string `json:"query"`
+	Count int64  `json:"count"`
+}
+
+type suggestionIndex struct {
+	mu   sync.RWMutex
+	root *trieNode
+}
+
+func (si *suggestionIndex) replace(root *trieNode) {
+	si.mu.Lock()
+	si.root = root
+	si.mu.Unlock()
+}
+
+func (si *suggestionIndex) lookup(prefix string, limit int) []*suggestion {
+	si.mu.RLock()
+	root := si.root
+	si.mu.RUnlock()
+
+	if root == nil {
+		return nil
+	}
+	node := root
+	for _, r := range prefix {
+		if node = node.children[r]; node == nil {
+			return nil
+		}
+	}
+	if len(node.top) < limit {
+		limit = len(node.top)
+	}
+	return node.top[:limit]
+}
+
+// trieTopK is the number of completions precomputed at every node.
+const trieTopK = maxSuggestLimit
+
+type trieNode struct {
+	children map[rune]*trieNode
+	// terminal is set if a query ends at this node.
+	terminal *suggestion
+	// top holds the most issued queries in this subtree,
+	// most issued first, so lookups don't have to walk it.
+	top []*suggestion
+}
+
+func newSuggestionTrie(counts map[string]int64) *trieNode {
+	root := &trieNode{children: make(map[rune]*trieNode)}
+	for query, count := range counts {
+		node := root
+		for _, r := range query {
+			child := node.children[r]
+			if child == nil {
+				child = &trieNode{children: make(map[rune]*trieNode)}
+				node.children[r] = child
+			}
+			node = child
+		}
+		node.terminal = &suggestion{Query: query, Count: count}
+	}
+	root.computeTop()
+	return root
+}
+
+func (tn *trieNode) computeTop() []*suggestion {
+	var top []*suggestion
+	if tn.terminal != nil {
+		top = append(top, tn.terminal)
+	}
+	for _, child := range tn.children {
+		top = append(top, child.computeTop()...)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		return top[i].Query < top[j].Query
+	})
+	if len(top) > trieTopK {
+		top = top[:trieTopK]
+	}
+	tn.top = top
+	return top
+}
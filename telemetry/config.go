@@ -0,0 +1,141 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/orijtech/media-search/config"
+)
+
+// Config selects the exporters to enable. The zero value enables none.
+type Config struct {
+	ServiceName string
+
+	// PrometheusAddr, if set, is the address on which
+	// the metrics are served for scraping at /metrics.
+	PrometheusAddr      string
+	PrometheusNamespace string
+
+	// StackdriverProjectID, if set, enables the Stackdriver exporter.
+	StackdriverProjectID    string
+	StackdriverMetricPrefix string
+
+	// XRay enables the AWS X-Ray exporter, which
+	// finds its credentials the usual AWS SDK way.
+	XRay bool
+
+	// Exactly one of the Jaeger endpoints is needed to enable the Jaeger exporter.
+	JaegerAgentEndpoint     string
+	JaegerCollectorEndpoint string
+
+	// ZipkinEndpoint, if set, is the URL to which spans are reported
+	// e.g. http://localhost:9411/api/v2/spans
+	ZipkinEndpoint string
+
+	// OTLPEndpoint, if set, is the host:port of an OTLP gRPC receiver.
+	OTLPEndpoint string
+	OTLPInsecure bool
+
+	// OpenTelemetry routes all the OpenCensus instrumentation through the
+	// OpenTelemetry SDK, which then exports to OTLPEndpoint if it is set.
+	OpenTelemetry bool
+
+	// Log writes spans and metrics to the standard logger.
+	Log bool
+
+	// LogLevel is one of debug, info, warn or error, and
+	// LogFormat either text or json, for NewLogger.
+	LogLevel  string
+	LogFormat string
+
+	// ZPagesAddr, if set, is the address on which zPages are served at
+	// /debug, along with the admin endpoints of the binary, see Handle.
+	ZPagesAddr string
+
+	// TLS, if set, serves the metrics and zPages over TLS. It
+	// isn't a setting of its own but that of the service's.
+	TLS *tls.Config
+
+	ReportingPeriod time.Duration
+
+	// SampleRate is the probability with which traces are sampled
+	// unless a SamplingOverrides entry applies, see SamplingPolicy.
+	SampleRate        float64
+	SamplingOverrides string
+}
+
+// tracesToOpenCensusExporters reports whether any OpenCensus trace exporter is enabled.
+func (c *Config) tracesToOpenCensusExporters() bool {
+	return c.StackdriverProjectID != "" || c.XRay || c.JaegerAgentEndpoint != "" ||
+		c.JaegerCollectorEndpoint != "" || c.ZipkinEndpoint != "" || c.Log
+}
+
+// Fields returns the settings of c, to be loaded by a config.Loader.
+func (c *Config) Fields() []*config.Field {
+	return []*config.Field{
+		{Name: "service-name", Env: "MEDIA_SEARCH_SERVICE_NAME", Usage: "the name under which this process reports telemetry", Value: &c.ServiceName},
+		{Name: "prometheus-addr", Env: "MEDIA_SEARCH_PROMETHEUS_ADDR", Usage: "if set, the address on which to serve Prometheus metrics", Value: &c.PrometheusAddr},
+		{Name: "prometheus-namespace", Env: "MEDIA_SEARCH_PROMETHEUS_NAMESPACE", Usage: "the namespace of the Prometheus metrics", Value: &c.PrometheusNamespace},
+		{Name: "stackdriver-project", Env: "OPENCENSUS_GCP_PROJECTID", Usage: "if set, the GCP project to which to export to Stackdriver", Value: &c.StackdriverProjectID},
+		{Name: "stackdriver-metric-prefix", Env: "MEDIA_SEARCH_STACKDRIVER_METRIC_PREFIX", Usage: "the prefix of the Stackdriver metrics", Value: &c.StackdriverMetricPrefix},
+		{Name: "xray", Env: "MEDIA_SEARCH_XRAY", Usage: "if set, export traces to AWS X-Ray", Value: &c.XRay},
+		{Name: "jaeger-agent", Env: "MEDIA_SEARCH_JAEGER_AGENT_ENDPOINT", Usage: "if set, the host:port of the Jaeger agent to export traces to", Value: &c.JaegerAgentEndpoint},
+		{Name: "jaeger-collector", Env: "MEDIA_SEARCH_JAEGER_COLLECTOR_ENDPOINT", Usage: "if set, the URL of the Jaeger collector to export traces to", Value: &c.JaegerCollectorEndpoint},
+		{Name: "zipkin-endpoint", Env: "MEDIA_SEARCH_ZIPKIN_ENDPOINT", Usage: "if set, the URL of the Zipkin server to export traces to", Value: &c.ZipkinEndpoint},
+		{Name: "otlp-endpoint", Env: "OTEL_EXPORTER_OTLP_ENDPOINT", Usage: "if set, the host:port of the OTLP gRPC receiver to export to", Value: &c.OTLPEndpoint},
+		{Name: "otlp-insecure", Env: "MEDIA_SEARCH_OTLP_INSECURE", Usage: "if set, connect to the OTLP receiver without TLS", Value: &c.OTLPInsecure},
+		{Name: "otel", Env: "MEDIA_SEARCH_OPENTELEMETRY", Usage: "if set, record through the OpenTelemetry SDK instead of OpenCensus", Value: &c.OpenTelemetry},
+		{Name: "log-exporter", Env: "MEDIA_SEARCH_LOG_EXPORTER", Usage: "if set, log spans and metrics", Value: &c.Log},
+		{Name: "log-level", Env: "MEDIA_SEARCH_LOG_LEVEL", Usage: "the minimum level of the lines logged: debug, info, warn or error", Value: &c.LogLevel},
+		{Name: "log-format", Env: "MEDIA_SEARCH_LOG_FORMAT", Usage: "the format of the lines logged: text or json", Value: &c.LogFormat},
+		{Name: "zpages-addr", Env: "MEDIA_SEARCH_ZPAGES_ADDR", Usage: "if set, the address on which to serve zPages", Value: &c.ZPagesAddr},
+		{Name: "reporting-period", Env: "MEDIA_SEARCH_REPORTING_PERIOD", Usage: "how often metrics are exported", Value: &c.ReportingPeriod},
+		{Name: "sample-rate", Env: "MEDIA_SEARCH_SAMPLE_RATE", Usage: "the probability with which traces are sampled, from 0 to 1", Value: &c.SampleRate},
+		{Name: "sampling-overrides", Env: "MEDIA_SEARCH_SAMPLING_OVERRIDES", Usage: `per span name prefix sample rates e.g. "/search=1,/analytics=0.01"`, Value: &c.SamplingOverrides},
+	}
+}
+
+// Validate returns an error if Setup or NewLogger would fail on c
+// because of a malformed setting, rather than an unreachable exporter.
+func (c *Config) Validate() error {
+	if err := config.CheckOptionalAddr("prometheus-addr", c.PrometheusAddr); err != nil {
+		return err
+	}
+	if err := config.CheckOptionalAddr("zpages-addr", c.ZPagesAddr); err != nil {
+		return err
+	}
+	if c.JaegerCollectorEndpoint != "" {
+		if err := config.CheckURL("jaeger-collector", c.JaegerCollectorEndpoint); err != nil {
+			return err
+		}
+	}
+	if c.ZipkinEndpoint != "" {
+		if err := config.CheckURL("zipkin-endpoint", c.ZipkinEndpoint); err != nil {
+			return err
+		}
+	}
+	if c.ReportingPeriod < 0 {
+		return fmt.Errorf("telemetry: reporting period %v is negative", c.ReportingPeriod)
+	}
+	if _, err := ParseSamplingPolicy(c.SampleRate, c.SamplingOverrides); err != nil {
+		return err
+	}
+	_, err := NewLogger(ioutil.Discard, c)
+	return err
+}
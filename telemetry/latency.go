@@ -0,0 +1,92 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+var (
+	KeyOperation = mustKey("operation")
+	KeyResult    = mustKey("result")
+	KeyProvider  = mustKey("provider")
+
+	stageLatency = stats.Float64("stage_latency", "The latency of a stage of serving a request", stats.UnitMilliseconds)
+)
+
+// The values of KeyResult.
+const (
+	ResultOK    = "ok"
+	ResultHit   = "hit"
+	ResultMiss  = "miss"
+	ResultError = "error"
+)
+
+// The values of KeyProvider.
+const (
+	ProviderMongoDB = "mongodb"
+	ProviderYouTube = "youtube"
+	ProviderGenID   = "genid"
+	ProviderSearch  = "search"
+)
+
+// Views are the views for the metrics recorded by this package,
+// which every service registers.
+var Views = []*view.View{
+	{
+		Name: "stage_latency", Description: "latency per stage by operation, result and provider",
+		Measure: stageLatency,
+		// Bounds in milliseconds, fine grained enough for the p50/p95/p99 of
+		// cache lookups at the low end and of YouTube API calls at the high end.
+		Aggregation: view.Distribution(0, 1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 2000, 5000, 10000, 30000),
+		TagKeys:     []tag.Key{KeyOperation, KeyResult, KeyProvider},
+	},
+}
+
+// RecordLatency records the time elapsed since start for
+// the operation, such as "cache_lookup", against provider.
+func RecordLatency(ctx context.Context, start time.Time, operation, provider, result string) {
+	ctx, err := tag.New(ctx,
+		tag.Upsert(KeyOperation, operation),
+		tag.Upsert(KeyProvider, provider),
+		tag.Upsert(KeyResult, result),
+	)
+	if err != nil {
+		return
+	}
+	stats.Record(ctx, stageLatency.M(float64(time.Since(start))/float64(time.Millisecond)))
+}
+
+// ResultOf returns ResultError if err is set, else ResultOK.
+func ResultOf(err error) string {
+	if err != nil {
+		return ResultError
+	}
+	return ResultOK
+}
+
+func mustKey(sk string) tag.Key {
+	k, err := tag.NewKey(sk)
+	if err != nil {
+		log.Fatalf("Creating new key %q error: %v", sk, err)
+	}
+	return k
+}
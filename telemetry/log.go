@@ -0,0 +1,41 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"log"
+
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/trace"
+)
+
+// logExporter writes spans and view data to the standard logger,
+// which is handy during local development.
+type logExporter struct{}
+
+var _ trace.Exporter = (*logExporter)(nil)
+var _ view.Exporter = (*logExporter)(nil)
+
+func (le *logExporter) ExportSpan(sd *trace.SpanData) {
+	log.Printf("span: name=%q trace=%s span=%s parent=%s latency=%s status=%d %q attributes=%v",
+		sd.Name, sd.TraceID, sd.SpanID, sd.ParentSpanID, sd.EndTime.Sub(sd.StartTime),
+		sd.Status.Code, sd.Status.Message, sd.Attributes)
+}
+
+func (le *logExporter) ExportView(vd *view.Data) {
+	for _, row := range vd.Rows {
+		log.Printf("view: name=%q tags=%v data=%+v", vd.View.Name, row.Tags, row.Data)
+	}
+}
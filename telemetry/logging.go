@@ -0,0 +1,83 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opencensus.io/trace"
+)
+
+// NewLogger returns a logger writing to w at the level and in the format
+// set in cfg. Every line logged with a context carrying a span, e.g. by
+// logger.InfoContext(ctx, ...), has its trace_id and span_id so that it
+// can be joined to the trace, in either OpenCensus or OpenTelemetry mode.
+func NewLogger(w io.Writer, cfg *Config) (*slog.Logger, error) {
+	var level slog.Level
+	if cfg.LogLevel != "" {
+		if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+			return nil, fmt.Errorf("telemetry: log level %q: %v", cfg.LogLevel, err)
+		}
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	var h slog.Handler
+	switch strings.ToLower(cfg.LogFormat) {
+	case "", "text":
+		h = slog.NewTextHandler(w, opts)
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("telemetry: log format %q is neither text nor json", cfg.LogFormat)
+	}
+
+	logger := slog.New(&traceHandler{h})
+	if cfg.ServiceName != "" {
+		logger = logger.With("service", cfg.ServiceName)
+	}
+	return logger, nil
+}
+
+// traceHandler adds the IDs of the span in the context to every record,
+// within the innermost group if any like any other attribute of the record.
+type traceHandler struct {
+	slog.Handler
+}
+
+var _ slog.Handler = (*traceHandler)(nil)
+
+func (th *traceHandler) Handle(ctx context.Context, r slog.Record) error {
+	if span := trace.FromContext(ctx); span != nil {
+		sc := span.SpanContext()
+		r.AddAttrs(
+			slog.String("trace_id", sc.TraceID.String()),
+			slog.String("span_id", sc.SpanID.String()),
+			slog.Bool("trace_sampled", sc.IsSampled()),
+		)
+	}
+	return th.Handler.Handle(ctx, r)
+}
+
+func (th *traceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &traceHandler{th.Handler.WithAttrs(attrs)}
+}
+
+func (th *traceHandler) WithGroup(name string) slog.Handler {
+	return &traceHandler{th.Handler.WithGroup(name)}
+}
@@ -0,0 +1,84 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	ocbridge "go.opentelemetry.io/otel/bridge/opencensus"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// setupOpenTelemetry makes the OpenTelemetry SDK the destination of all
+// telemetry: the OpenCensus tracer is replaced by a bridge to the SDK's
+// tracer, and the OpenCensus views are read by the SDK's metric reader,
+// so the existing instrumentation and any written against the
+// OpenTelemetry API end up in the same traces and export pipeline.
+//
+// Metrics keep the names of the views that they come from.
+func setupOpenTelemetry(ctx context.Context, cfg *Config, sampling *SamplingPolicy) (*sdktrace.TracerProvider, *sdkmetric.MeterProvider, error) {
+	res := resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName))
+	traceOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(&otelSampler{policy: sampling}),
+	}
+	metricOpts := []sdkmetric.Option{sdkmetric.WithResource(res)}
+
+	if cfg.OTLPEndpoint != "" {
+		spanOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.OTLPEndpoint)}
+		viewOpts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(cfg.OTLPEndpoint)}
+		if cfg.OTLPInsecure {
+			spanOpts = append(spanOpts, otlptracegrpc.WithInsecure())
+			viewOpts = append(viewOpts, otlpmetricgrpc.WithInsecure())
+		}
+		spanExporter, err := otlptracegrpc.New(ctx, spanOpts...)
+		if err != nil {
+			return nil, nil, err
+		}
+		metricExporter, err := otlpmetricgrpc.New(ctx, viewOpts...)
+		if err != nil {
+			_ = spanExporter.Shutdown(ctx)
+			return nil, nil, err
+		}
+
+		interval := cfg.ReportingPeriod
+		if interval <= 0 {
+			interval = time.Minute
+		}
+		traceOpts = append(traceOpts, sdktrace.WithBatcher(spanExporter))
+		metricOpts = append(metricOpts, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter,
+			sdkmetric.WithInterval(interval),
+			sdkmetric.WithProducer(ocbridge.NewMetricProducer()),
+		)))
+	}
+
+	tp := sdktrace.NewTracerProvider(traceOpts...)
+	mp := sdkmetric.NewMeterProvider(metricOpts...)
+	otel.SetTracerProvider(tp)
+	otel.SetMeterProvider(mp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	// Spans are now started by tp, hence sampled by otelSampler.
+	ocbridge.InstallTraceBridge(ocbridge.WithTracerProvider(tp))
+	return tp, mp, nil
+}
@@ -0,0 +1,307 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.opencensus.io/trace"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const (
+	otlpBatchSize     = 512
+	otlpFlushInterval = 5 * time.Second
+	otlpTimeout       = 10 * time.Second
+)
+
+// instrumentationScope names the origin of everything
+// converted from OpenCensus and exported over OTLP.
+var instrumentationScope = instrumentation.Scope{Name: "go.opencensus.io"}
+
+// otlpExporter converts OpenCensus spans and view data
+// to OTLP and sends them to an OTLP gRPC receiver.
+type otlpExporter struct {
+	traces   *otlptrace.Exporter
+	metrics  *otlpmetricgrpc.Exporter
+	resource *resource.Resource
+
+	mu    sync.Mutex
+	spans []sdktrace.ReadOnlySpan
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+var _ trace.Exporter = (*otlpExporter)(nil)
+var _ view.Exporter = (*otlpExporter)(nil)
+
+func newOTLPExporter(ctx context.Context, cfg *Config) (*otlpExporter, error) {
+	traceOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.OTLPEndpoint)}
+	metricOpts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(cfg.OTLPEndpoint)}
+	if cfg.OTLPInsecure {
+		traceOpts = append(traceOpts, otlptracegrpc.WithInsecure())
+		metricOpts = append(metricOpts, otlpmetricgrpc.WithInsecure())
+	}
+	traces, err := otlptracegrpc.New(ctx, traceOpts...)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := otlpmetricgrpc.New(ctx, metricOpts...)
+	if err != nil {
+		_ = traces.Shutdown(ctx)
+		return nil, err
+	}
+
+	oe := &otlpExporter{
+		traces:   traces,
+		metrics:  metrics,
+		resource: resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName)),
+		done:     make(chan struct{}),
+	}
+	oe.wg.Add(1)
+	go oe.flushPeriodically()
+	return oe, nil
+}
+
+// ExportSpan is invoked synchronously as spans end,
+// so it only buffers them for the next flush.
+func (oe *otlpExporter) ExportSpan(sd *trace.SpanData) {
+	span := oe.toReadOnlySpan(sd)
+	oe.mu.Lock()
+	oe.spans = append(oe.spans, span)
+	full := len(oe.spans) >= otlpBatchSize
+	oe.mu.Unlock()
+	if full {
+		go oe.Flush()
+	}
+}
+
+func (oe *otlpExporter) flushPeriodically() {
+	defer oe.wg.Done()
+	ticker := time.NewTicker(otlpFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			oe.Flush()
+		case <-oe.done:
+			return
+		}
+	}
+}
+
+// Flush exports the buffered spans.
+func (oe *otlpExporter) Flush() {
+	oe.mu.Lock()
+	spans := oe.spans
+	oe.spans = nil
+	oe.mu.Unlock()
+	if len(spans) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), otlpTimeout)
+	defer cancel()
+	if err := oe.traces.ExportSpans(ctx, spans); err != nil {
+		log.Printf("OTLP: exporting %d spans error: %v", len(spans), err)
+	}
+}
+
+func (oe *otlpExporter) Close() error {
+	close(oe.done)
+	oe.wg.Wait()
+	oe.Flush()
+
+	ctx, cancel := context.WithTimeout(context.Background(), otlpTimeout)
+	defer cancel()
+	terr := oe.traces.Shutdown(ctx)
+	merr := oe.metrics.Shutdown(ctx)
+	if terr != nil {
+		return terr
+	}
+	return merr
+}
+
+func (oe *otlpExporter) toReadOnlySpan(sd *trace.SpanData) sdktrace.ReadOnlySpan {
+	stub := tracetest.SpanStub{
+		Name: sd.Name,
+		SpanContext: oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+			TraceID:    oteltrace.TraceID(sd.TraceID),
+			SpanID:     oteltrace.SpanID(sd.SpanID),
+			TraceFlags: oteltrace.TraceFlags(sd.TraceOptions),
+		}),
+		StartTime:            sd.StartTime,
+		EndTime:              sd.EndTime,
+		Attributes:           toAttributes(sd.Attributes),
+		Resource:             oe.resource,
+		InstrumentationScope: instrumentationScope,
+	}
+	if sd.ParentSpanID != (trace.SpanID{}) {
+		stub.Parent = oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+			TraceID: oteltrace.TraceID(sd.TraceID),
+			SpanID:  oteltrace.SpanID(sd.ParentSpanID),
+			Remote:  sd.HasRemoteParent,
+		})
+	}
+	switch sd.SpanKind {
+	case trace.SpanKindServer:
+		stub.SpanKind = oteltrace.SpanKindServer
+	case trace.SpanKindClient:
+		stub.SpanKind = oteltrace.SpanKindClient
+	default:
+		stub.SpanKind = oteltrace.SpanKindInternal
+	}
+	for _, annotation := range sd.Annotations {
+		stub.Events = append(stub.Events, sdktrace.Event{
+			Name:       annotation.Message,
+			Time:       annotation.Time,
+			Attributes: toAttributes(annotation.Attributes),
+		})
+	}
+	if sd.Status.Code != trace.StatusCodeOK {
+		stub.Status = sdktrace.Status{Code: codes.Error, Description: sd.Status.Message}
+	}
+	return stub.Snapshot()
+}
+
+func toAttributes(attrs map[string]interface{}) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for key, value := range attrs {
+		switch value := value.(type) {
+		case string:
+			kvs = append(kvs, attribute.String(key, value))
+		case bool:
+			kvs = append(kvs, attribute.Bool(key, value))
+		case int64:
+			kvs = append(kvs, attribute.Int64(key, value))
+		case float64:
+			kvs = append(kvs, attribute.Float64(key, value))
+		default:
+			kvs = append(kvs, attribute.String(key, fmt.Sprint(value)))
+		}
+	}
+	return kvs
+}
+
+// ExportView sends the cumulative data of a view, keeping its name
+// so that metrics look the same whichever exporter they came through.
+func (oe *otlpExporter) ExportView(vd *view.Data) {
+	m := metricdata.Metrics{
+		Name:        vd.View.Name,
+		Description: vd.View.Description,
+		Unit:        vd.View.Measure.Unit(),
+	}
+
+	switch vd.View.Aggregation.Type {
+	case view.AggTypeCount:
+		sum := metricdata.Sum[int64]{Temporality: metricdata.CumulativeTemporality, IsMonotonic: true}
+		for _, row := range vd.Rows {
+			if data, ok := row.Data.(*view.CountData); ok {
+				sum.DataPoints = append(sum.DataPoints, metricdata.DataPoint[int64]{
+					Attributes: toAttributeSet(row.Tags), StartTime: vd.Start, Time: vd.End, Value: data.Value,
+				})
+			}
+		}
+		m.Data = sum
+
+	case view.AggTypeSum:
+		sum := metricdata.Sum[float64]{Temporality: metricdata.CumulativeTemporality}
+		for _, row := range vd.Rows {
+			if data, ok := row.Data.(*view.SumData); ok {
+				sum.DataPoints = append(sum.DataPoints, metricdata.DataPoint[float64]{
+					Attributes: toAttributeSet(row.Tags), StartTime: vd.Start, Time: vd.End, Value: data.Value,
+				})
+			}
+		}
+		m.Data = sum
+
+	case view.AggTypeLastValue:
+		var gauge metricdata.Gauge[float64]
+		for _, row := range vd.Rows {
+			if data, ok := row.Data.(*view.LastValueData); ok {
+				gauge.DataPoints = append(gauge.DataPoints, metricdata.DataPoint[float64]{
+					Attributes: toAttributeSet(row.Tags), Time: vd.End, Value: data.Value,
+				})
+			}
+		}
+		m.Data = gauge
+
+	case view.AggTypeDistribution:
+		histogram := metricdata.Histogram[float64]{Temporality: metricdata.CumulativeTemporality}
+		for _, row := range vd.Rows {
+			data, ok := row.Data.(*view.DistributionData)
+			if !ok {
+				continue
+			}
+			counts := make([]uint64, len(data.CountPerBucket))
+			for i, count := range data.CountPerBucket {
+				counts[i] = uint64(count)
+			}
+			histogram.DataPoints = append(histogram.DataPoints, metricdata.HistogramDataPoint[float64]{
+				Attributes:   toAttributeSet(row.Tags),
+				StartTime:    vd.Start,
+				Time:         vd.End,
+				Count:        uint64(data.Count),
+				Bounds:       vd.View.Aggregation.Buckets,
+				BucketCounts: counts,
+				Min:          metricdata.NewExtrema(data.Min),
+				Max:          metricdata.NewExtrema(data.Max),
+				Sum:          data.Mean * float64(data.Count),
+			})
+		}
+		m.Data = histogram
+
+	default:
+		return
+	}
+
+	rm := &metricdata.ResourceMetrics{
+		Resource: oe.resource,
+		ScopeMetrics: []metricdata.ScopeMetrics{
+			{Scope: instrumentationScope, Metrics: []metricdata.Metrics{m}},
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), otlpTimeout)
+	defer cancel()
+	if err := oe.metrics.Export(ctx, rm); err != nil {
+		log.Printf("OTLP: exporting view %q error: %v", vd.View.Name, err)
+	}
+}
+
+func toAttributeSet(tags []tag.Tag) attribute.Set {
+	kvs := make([]attribute.KeyValue, 0, len(tags))
+	for _, t := range tags {
+		kvs = append(kvs, attribute.String(t.Key.Name(), t.Value))
+	}
+	return attribute.NewSet(kvs...)
+}
@@ -0,0 +1,188 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.opencensus.io/plugin/ochttp/propagation/b3"
+	"go.opencensus.io/plugin/ochttp/propagation/tracecontext"
+	"go.opencensus.io/trace"
+	"go.opencensus.io/trace/propagation"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// SamplingPolicy decides which traces are sampled. A span whose parent
+// made a decision, whether in this process or in an incoming W3C
+// traceparent or B3 header, follows that decision. Otherwise the span is
+// sampled with the probability of the longest override that prefixes
+// its name e.g. "/search" for the frontend's HTTP endpoint, else Rate.
+type SamplingPolicy struct {
+	Rate      float64
+	Overrides map[string]float64
+
+	// prefixes are the keys of Overrides, longest first.
+	prefixes []string
+}
+
+// ParseSamplingPolicy returns the policy with the default rate and
+// overrides of the form "/search=1,/analytics=0.01".
+func ParseSamplingPolicy(rate float64, overrides string) (*SamplingPolicy, error) {
+	if rate < 0 || rate > 1 {
+		return nil, fmt.Errorf("telemetry: sample rate %v is not in [0, 1]", rate)
+	}
+	sp := &SamplingPolicy{Rate: rate, Overrides: make(map[string]float64)}
+	for _, override := range strings.Split(overrides, ",") {
+		if override = strings.TrimSpace(override); override == "" {
+			continue
+		}
+		i := strings.LastIndex(override, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("telemetry: sampling override %q is not of the form name=rate", override)
+		}
+		name := strings.TrimSpace(override[:i])
+		rate, err := strconv.ParseFloat(strings.TrimSpace(override[i+1:]), 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("telemetry: sampling override %q must have a rate in [0, 1]", override)
+		}
+		sp.Overrides[name] = rate
+		sp.prefixes = append(sp.prefixes, name)
+	}
+	sort.Slice(sp.prefixes, func(i, j int) bool { return len(sp.prefixes[i]) > len(sp.prefixes[j]) })
+	return sp, nil
+}
+
+func (sp *SamplingPolicy) rateFor(name string) float64 {
+	for _, prefix := range sp.prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return sp.Overrides[prefix]
+		}
+	}
+	return sp.Rate
+}
+
+// shouldSample is consistent across services for the same trace
+// since it only depends on the trace ID and the rate.
+func (sp *SamplingPolicy) shouldSample(traceID [16]byte, name string) bool {
+	rate := sp.rateFor(name)
+	switch {
+	case rate >= 1:
+		return true
+	case rate <= 0:
+		return false
+	}
+	upperBound := uint64(rate * (1 << 63))
+	return binary.BigEndian.Uint64(traceID[0:8])>>1 < upperBound
+}
+
+// Sampler returns the policy as an OpenCensus sampler.
+func (sp *SamplingPolicy) Sampler() trace.Sampler {
+	return func(p trace.SamplingParameters) trace.SamplingDecision {
+		if p.ParentContext.SpanID != (trace.SpanID{}) {
+			return trace.SamplingDecision{Sample: p.ParentContext.IsSampled()}
+		}
+		return trace.SamplingDecision{Sample: sp.shouldSample(p.TraceID, p.Name)}
+	}
+}
+
+// otelSampler applies the policy to spans started through the OpenTelemetry SDK.
+type otelSampler struct {
+	policy *SamplingPolicy
+}
+
+var _ sdktrace.Sampler = (*otelSampler)(nil)
+
+func (s *otelSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	parent := oteltrace.SpanContextFromContext(p.ParentContext)
+	sample := false
+	switch {
+	case sampledOnPurpose(p.ParentContext):
+		sample = true
+	case parent.IsValid():
+		sample = parent.IsSampled()
+	default:
+		sample = s.policy.shouldSample(p.TraceID, p.Name)
+	}
+	result := sdktrace.SamplingResult{Decision: sdktrace.Drop, Tracestate: parent.TraceState()}
+	if sample {
+		result.Decision = sdktrace.RecordAndSample
+	}
+	return result
+}
+
+func (s *otelSampler) Description() string {
+	return fmt.Sprintf("MediaSearchSampler{rate=%v,overrides=%v}", s.policy.Rate, s.policy.Overrides)
+}
+
+type sampledOnPurposeKey struct{}
+
+func sampledOnPurpose(ctx context.Context) bool {
+	forced, _ := ctx.Value(sampledOnPurposeKey{}).(bool)
+	return forced
+}
+
+// StartSampledSpan starts a span that is sampled regardless of the policy,
+// as are its descendants, for work that should always be traced such as
+// handling cache misses, even if the rest of the trace isn't sampled.
+func StartSampledSpan(ctx context.Context, name string) (context.Context, *trace.Span) {
+	ctx = context.WithValue(ctx, sampledOnPurposeKey{}, true)
+	return trace.StartSpan(ctx, name, trace.WithSampler(trace.AlwaysSample()))
+}
+
+// RecordError marks the span in ctx as failed with err. If that span isn't
+// sampled, a sampled child span records the error instead so that every
+// error shows up in the traces.
+func RecordError(ctx context.Context, err error) {
+	if err == nil {
+		return
+	}
+	status := trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()}
+	if span := trace.FromContext(ctx); span != nil && span.SpanContext().IsSampled() {
+		span.SetStatus(status)
+		return
+	}
+	_, span := StartSampledSpan(ctx, "error")
+	span.SetStatus(status)
+	span.End()
+}
+
+// HTTPFormat extracts the incoming trace context from either a W3C
+// traceparent or a B3 header, and injects both into outgoing requests.
+var HTTPFormat propagation.HTTPFormat = compositeFormat{&tracecontext.HTTPFormat{}, &b3.HTTPFormat{}}
+
+type compositeFormat []propagation.HTTPFormat
+
+func (cf compositeFormat) SpanContextFromRequest(req *http.Request) (trace.SpanContext, bool) {
+	for _, format := range cf {
+		if sc, ok := format.SpanContextFromRequest(req); ok {
+			return sc, true
+		}
+	}
+	return trace.SpanContext{}, false
+}
+
+func (cf compositeFormat) SpanContextToRequest(sc trace.SpanContext, req *http.Request) {
+	for _, format := range cf {
+		format.SpanContextToRequest(sc, req)
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package telemetry sets up the trace and metrics exporters shared
+// by every binary. Each exporter is opt-in, so that a process with
+// nothing configured starts up fine e.g. on an offline machine.
+package telemetry
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net/http"
+
+	"contrib.go.opencensus.io/exporter/jaeger"
+	"contrib.go.opencensus.io/exporter/stackdriver"
+	"contrib.go.opencensus.io/exporter/zipkin"
+	xray "github.com/census-instrumentation/opencensus-go-exporter-aws"
+	openzipkin "github.com/openzipkin/zipkin-go"
+	zipkinHTTP "github.com/openzipkin/zipkin-go/reporter/http"
+	"go.opencensus.io/exporter/prometheus"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/trace"
+	"go.opencensus.io/zpages"
+)
+
+// Telemetry holds the exporters started by Setup.
+type Telemetry struct {
+	names    []string
+	flushers []func()
+	closers  []func() error
+
+	tlsConfig *tls.Config
+	// zpagesMux is the ServeMux of the zPages listener, if any.
+	zpagesMux *http.ServeMux
+}
+
+// Setup applies the sampling policy, registers every exporter enabled
+// in cfg and starts the Prometheus and zPages servers if configured.
+func Setup(cfg *Config) (*Telemetry, error) {
+	sampling, err := ParseSamplingPolicy(cfg.SampleRate, cfg.SamplingOverrides)
+	if err != nil {
+		return nil, err
+	}
+	trace.ApplyConfig(trace.Config{DefaultSampler: sampling.Sampler()})
+
+	t := &Telemetry{tlsConfig: cfg.TLS}
+	ok := false
+	defer func() {
+		if !ok {
+			t.Close()
+		}
+	}()
+
+	if cfg.PrometheusAddr != "" {
+		pe, err := prometheus.NewExporter(prometheus.Options{Namespace: cfg.PrometheusNamespace})
+		if err != nil {
+			return nil, fmt.Errorf("telemetry: Prometheus newExporter: %v", err)
+		}
+		view.RegisterExporter(pe)
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", pe)
+		t.serve("prometheus", cfg.PrometheusAddr, mux)
+	}
+
+	if cfg.StackdriverProjectID != "" {
+		se, err := stackdriver.NewExporter(stackdriver.Options{
+			ProjectID:    cfg.StackdriverProjectID,
+			MetricPrefix: cfg.StackdriverMetricPrefix,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("telemetry: Stackdriver newExporter: %v", err)
+		}
+		trace.RegisterExporter(se)
+		view.RegisterExporter(se)
+		t.add("stackdriver", se.Flush, nil)
+	}
+
+	if cfg.XRay {
+		xe, err := xray.NewExporter(xray.WithVersion("latest"))
+		if err != nil {
+			return nil, fmt.Errorf("telemetry: AWS X-Ray newExporter: %v", err)
+		}
+		trace.RegisterExporter(xe)
+		t.add("xray", xe.Flush, xe.Close)
+	}
+
+	if cfg.JaegerAgentEndpoint != "" || cfg.JaegerCollectorEndpoint != "" {
+		je, err := jaeger.NewExporter(jaeger.Options{
+			AgentEndpoint:     cfg.JaegerAgentEndpoint,
+			CollectorEndpoint: cfg.JaegerCollectorEndpoint,
+			Process:           jaeger.Process{ServiceName: cfg.ServiceName},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("telemetry: Jaeger newExporter: %v", err)
+		}
+		trace.RegisterExporter(je)
+		t.add("jaeger", je.Flush, nil)
+	}
+
+	if cfg.ZipkinEndpoint != "" {
+		localEndpoint, err := openzipkin.NewEndpoint(cfg.ServiceName, "")
+		if err != nil {
+			return nil, fmt.Errorf("telemetry: Zipkin local endpoint: %v", err)
+		}
+		reporter := zipkinHTTP.NewReporter(cfg.ZipkinEndpoint)
+		trace.RegisterExporter(zipkin.NewExporter(reporter, localEndpoint))
+		t.add("zipkin", nil, reporter.Close)
+	}
+
+	switch {
+	case cfg.OpenTelemetry:
+		if cfg.tracesToOpenCensusExporters() {
+			log.Printf("The OpenCensus trace exporters receive no spans while OpenTelemetry is enabled")
+		}
+		tp, mp, err := setupOpenTelemetry(context.Background(), cfg, sampling)
+		if err != nil {
+			return nil, fmt.Errorf("telemetry: OpenTelemetry setup: %v", err)
+		}
+		flush := func() {
+			ctx, cancel := context.WithTimeout(context.Background(), otlpTimeout)
+			defer cancel()
+			_ = tp.ForceFlush(ctx)
+			_ = mp.ForceFlush(ctx)
+		}
+		shutdown := func() error {
+			ctx, cancel := context.WithTimeout(context.Background(), otlpTimeout)
+			defer cancel()
+			terr := tp.Shutdown(ctx)
+			merr := mp.Shutdown(ctx)
+			if terr != nil {
+				return terr
+			}
+			return merr
+		}
+		t.add("opentelemetry", flush, shutdown)
+
+	case cfg.OTLPEndpoint != "":
+		oe, err := newOTLPExporter(context.Background(), cfg)
+		if err != nil {
+			return nil, fmt.Errorf("telemetry: OTLP newExporter: %v", err)
+		}
+		trace.RegisterExporter(oe)
+		view.RegisterExporter(oe)
+		t.add("otlp", oe.Flush, oe.Close)
+	}
+
+	if cfg.Log {
+		le := new(logExporter)
+		trace.RegisterExporter(le)
+		view.RegisterExporter(le)
+		t.add("log", nil, nil)
+	}
+
+	if cfg.ZPagesAddr != "" {
+		mux := http.NewServeMux()
+		zpages.Handle(mux, "/debug")
+		t.serve("zpages", cfg.ZPagesAddr, mux)
+		t.zpagesMux = mux
+	}
+
+	if cfg.ReportingPeriod > 0 {
+		view.SetReportingPeriod(cfg.ReportingPeriod)
+	}
+
+	if len(t.names) == 0 {
+		log.Printf("No telemetry exporters configured")
+	} else {
+		log.Printf("Enabled telemetry exporters: %v", t.names)
+	}
+	ok = true
+	return t, nil
+}
+
+func (t *Telemetry) add(name string, flush func(), close func() error) {
+	t.names = append(t.names, name)
+	if flush != nil {
+		t.flushers = append(t.flushers, flush)
+	}
+	if close != nil {
+		t.closers = append(t.closers, close)
+	}
+}
+
+// serve serves h on addr in the background. Failing to serve telemetry
+// is logged rather than fatal so that it never takes the service down.
+func (t *Telemetry) serve(name, addr string, h http.Handler) {
+	srv := &http.Server{Addr: addr, Handler: h, TLSConfig: t.tlsConfig}
+	go func() {
+		var err error
+		if srv.TLSConfig != nil {
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			log.Printf("Serving %s on %q error: %v", name, addr, err)
+		}
+	}()
+	t.add(name, nil, srv.Close)
+}
+
+// Handle serves h at pattern on the zPages listener, which is for the
+// operators rather than the public, and reports false if there is none.
+func (t *Telemetry) Handle(pattern string, h http.Handler) bool {
+	if t.zpagesMux == nil {
+		return false
+	}
+	t.zpagesMux.Handle(pattern, h)
+	return true
+}
+
+// Flush exports any buffered spans and metrics.
+func (t *Telemetry) Flush() {
+	for _, flush := range t.flushers {
+		flush()
+	}
+}
+
+// Close flushes and then shuts down the exporters and servers.
+func (t *Telemetry) Close() error {
+	t.Flush()
+	var firstErr error
+	for _, close := range t.closers {
+		if err := close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,264 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/replaceopt"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+	"go.opencensus.io/trace"
+
+	"github.com/orijtech/media-search/rpc"
+	"github.com/orijtech/media-search/telemetry"
+)
+
+var ytTrendingCollection *mongo.Collection
+
+// trendingRefreshInterval is set from the configuration, see frontendConfig.
+var trendingRefreshInterval time.Duration
+
+var trendingRefreshes = stats.Int64("trending_refreshes", "the number of trending feed refreshes", stats.UnitNone)
+
+// trendingLocks ensures that only one request per feed refreshes it,
+// the others wait and are then served the newly cached copy.
+var trendingLocks = &keyedMutex{locks: make(map[string]*refMutex)}
+
+func trending(w http.ResponseWriter, r *http.Request) {
+	ctx, span := trace.StartSpan(r.Context(), "/trending")
+	defer span.End()
+
+	tq, err := rpc.ExtractTrendingQuery(ctx, r)
+	if err != nil {
+		rpc.WriteHTTPError(w, err)
+		return
+	}
+
+	outBlob, err := trendingFeed(ctx, tq)
+	if err == nil {
+		outBlob, err = firstTrending(outBlob, int(tq.MaxResults))
+	}
+	if err != nil {
+		rpc.WriteHTTPError(w, err)
+		return
+	}
+	_, _ = w.Write(outBlob)
+}
+
+// trendingKV is the cached copy of a feed. If its last refresh failed
+// with nothing to fall back to, it holds the error instead, so that the
+// failure is served until the next refresh rather than retried per request.
+type trendingKV struct {
+	Key        string    `bson:"key,omitempty"`
+	Value      []byte    `bson:"value,omitempty"`
+	ErrCode    string    `bson:"err_code,omitempty"`
+	ErrMessage string    `bson:"err_message,omitempty"`
+	CacheTime  time.Time `bson:"ct,omitempty"`
+}
+
+var blankTrendingKV = new(trendingKV)
+
+func (kv *trendingKV) feed() ([]byte, error) {
+	if kv.ErrCode != "" {
+		return nil, &rpc.Error{Code: rpc.Code(kv.ErrCode), Message: kv.ErrMessage}
+	}
+	return kv.Value, nil
+}
+
+// trendingFeed returns the feed for tq with all of its MaxTrendingResults,
+// which is what is fetched and cached whatever tq.MaxResults is.
+func trendingFeed(ctx context.Context, tq *rpc.TrendingQuery) ([]byte, error) {
+	ctx, span := trace.StartSpan(ctx, "trending-feed")
+	defer span.End()
+
+	key := tq.CacheKey()
+
+	// 1. Firstly check if the feed was cached recently enough.
+	if cachedKV, fresh := lookupTrending(ctx, key); fresh {
+		span.Annotate([]trace.Attribute{
+			trace.BoolAttribute("hit", true),
+			trace.StringAttribute("key", key),
+		}, "Trending cache hit")
+		return cachedKV.feed()
+	}
+
+	unlock := trendingLocks.lock(key)
+	defer unlock()
+
+	// 2. Someone else might have refreshed it while we waited.
+	cachedKV, fresh := lookupTrending(ctx, key)
+	if fresh {
+		return cachedKV.feed()
+	}
+	if cachedKV != nil && cachedKV.ErrCode != "" {
+		// Only a feed is worth falling back to.
+		cachedKV = nil
+	}
+
+	span.Annotate([]trace.Attribute{
+		trace.BoolAttribute("hit", false),
+		trace.StringAttribute("key", key),
+	}, "Trending cache miss or stale, hence YouTube API lookup")
+	ctx, missSpan := telemetry.StartSampledSpan(ctx, "cache-miss")
+	defer missSpan.End()
+
+	fullTQ := &rpc.TrendingQuery{
+		RegionCode: tq.RegionCode,
+		CategoryId: tq.CategoryId,
+		MaxResults: rpc.MaxTrendingResults,
+	}
+	trendingStart := time.Now()
+	results, err := searchClient.Trending(ctx, fullTQ)
+	telemetry.RecordLatency(ctx, trendingStart, "trending", telemetry.ProviderSearch, telemetry.ResultOf(err))
+	if err != nil {
+		stats.Record(ctx, youtubeAPIErrors.M(1))
+		span.Annotate([]trace.Attribute{
+			trace.StringAttribute("api_error", err.Error()),
+		}, "YouTube API trending error")
+		telemetry.RecordError(ctx, err)
+		logger.ErrorContext(ctx, "Trending error", "key", key, "stale_fallback", cachedKV != nil, "err", err)
+		if ctx.Err() != nil {
+			// The request was canceled, which says nothing about the feed.
+			return nil, err
+		}
+		failedKV := cachedKV
+		if failedKV == nil {
+			rerr := rpc.FromError(err)
+			failedKV = &trendingKV{Key: key, ErrCode: string(rerr.Code), ErrMessage: rerr.Message}
+		}
+		// A stale feed is better than no feed at all, else the error is
+		// served, and either way it isn't retried until the next refresh.
+		failedKV.CacheTime = time.Now()
+		storeTrending(ctx, failedKV)
+		return failedKV.feed()
+	}
+	stats.Record(ctx, trendingRefreshes.M(1))
+
+	outBlob, err := json.Marshal(results)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Now replace the cached feed so that it'll be a hit until the next refresh.
+	storeTrending(ctx, &trendingKV{
+		Key:       key,
+		Value:     outBlob,
+		CacheTime: time.Now(),
+	})
+
+	return outBlob, nil
+}
+
+// firstTrending slices the first n results off the feed in blob.
+func firstTrending(blob []byte, n int) ([]byte, error) {
+	results := new(rpc.TrendingResults)
+	if err := json.Unmarshal(blob, results); err != nil {
+		return nil, err
+	}
+	if len(results.Items) <= n {
+		return blob, nil
+	}
+	results.Items = results.Items[:n]
+	return json.Marshal(results)
+}
+
+// storeTrending replaces the cached copy of the feed with kv, in a single
+// upsert so that concurrent refreshes can't leave duplicates behind nor a
+// failed one leave nothing cached.
+func storeTrending(ctx context.Context, kv *trendingKV) {
+	filter := bson.NewDocument(bson.EC.String("key", kv.Key))
+	replaceStart := time.Now()
+	_, err := ytTrendingCollection.ReplaceOne(ctx, filter, kv, replaceopt.Upsert(true))
+	telemetry.RecordLatency(ctx, replaceStart, "trending_cache_insert", telemetry.ProviderMongoDB, telemetry.ResultOf(err))
+	if err != nil {
+		ctx, _ = tag.New(ctx, tag.Upsert(keyCacheType, "mongo"))
+		stats.Record(ctx, cacheInsertionErrors.M(1))
+	}
+}
+
+// lookupTrending returns the cached feed for key if any,
+// reporting whether it is still within the refresh interval.
+func lookupTrending(ctx context.Context, key string) (cachedKV *trendingKV, fresh bool) {
+	start := time.Now()
+	result := telemetry.ResultMiss
+	defer func() {
+		if fresh {
+			result = telemetry.ResultHit
+		}
+		telemetry.RecordLatency(ctx, start, "trending_cache_lookup", telemetry.ProviderMongoDB, result)
+	}()
+
+	filter := bson.NewDocument(bson.EC.String("key", key))
+	cachedKV = new(trendingKV)
+
+	switch err := ytTrendingCollection.FindOne(ctx, filter).Decode(cachedKV); err {
+	case nil:
+		if reflect.DeepEqual(cachedKV, blankTrendingKV) {
+			return nil, false
+		}
+		return cachedKV, time.Since(cachedKV.CacheTime) < trendingRefreshInterval
+
+	case bson.ErrElementNotFound, mongo.ErrNoDocuments:
+		return nil, false
+
+	default:
+		result = telemetry.ResultError
+		stats.Record(ctx, mongoErrors.M(1))
+		return nil, false
+	}
+}
+
+// keyedMutex is a mutex per key, kept only while it is held or waited for.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*refMutex
+}
+
+// refMutex is a mutex with the number of callers holding or waiting for it.
+type refMutex struct {
+	sync.Mutex
+	refs int
+}
+
+func (km *keyedMutex) lock(key string) (unlock func()) {
+	km.mu.Lock()
+	l, ok := km.locks[key]
+	if !ok {
+		l = new(refMutex)
+		km.locks[key] = l
+	}
+	l.refs++
+	km.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+
+		km.mu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(km.locks, key)
+		}
+		km.mu.Unlock()
+	}
+}